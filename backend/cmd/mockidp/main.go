@@ -0,0 +1,31 @@
+// Минимальный OpenID Connect провайдер для локальной проверки входа.
+// Не использовать в продакшене: пускает любого пользователя без пароля.
+//
+//	go run ./cmd/mockidp -addr :9000
+//	OIDC_ISSUER_URL=http://localhost:9000 OIDC_CLIENT_ID=fitly \
+//	OIDC_REDIRECT_URL=http://localhost:8080/auth/callback go run ./cmd/fitly
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/oidc/mockidp"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalln("failed to generate signing key:", err)
+	}
+
+	log.Println("mock idp is running on", *addr, "with issuer", *issuer)
+	log.Fatalln(http.ListenAndServe(*addr, mockidp.New(*issuer, key)))
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Все данные пользователя для выгрузки
+type UserExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	User       User            `json:"user"`
+	Profile    *Profile        `json:"profile"`
+	Dishes     []Dish          `json:"dishes"`
+	Diary      []DiaryEntry    `json:"diary"`
+	Weight     []WeightEntry   `json:"weight_log"`
+	Exercise   []ExerciseEntry `json:"exercise_log"`
+	MealPlans  []MealPlan      `json:"meal_plans"`
+	Shopping   []ShoppingList  `json:"shopping_lists"`
+	Pantry     []PantryItem    `json:"pantry"`
+	Products   []Product       `json:"submitted_products"`
+	APIKeys    []APIKey        `json:"api_keys"`
+	Sessions   []Session       `json:"sessions"`
+}
+
+// Собрать все, что хранится о пользователе
+func ExportUserData(userID uint) (*UserExport, error) {
+	export := &UserExport{ExportedAt: time.Now()}
+
+	if err := DB.First(&export.User, userID).Error; err != nil {
+		return nil, err
+	}
+	var profile Profile
+	if err := DB.Where("user_id = ?", userID).Limit(1).Find(&profile).Error; err != nil {
+		return nil, err
+	}
+	if profile.ID != 0 {
+		export.Profile = &profile
+	}
+	if err := DB.Scopes(PreloadProduct("Ingredients.Product")).Where("user_id = ?", userID).Order("id").Find(&export.Dishes).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", userID).Order("date, id").Find(&export.Diary).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", userID).Order("date").Find(&export.Weight).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Preload("Activity").Where("user_id = ?", userID).Order("date, id").Find(&export.Exercise).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).
+		Where("user_id = ?", userID).Order("id").Find(&export.MealPlans).Error; err != nil {
+		return nil, err
+	}
+	shared := DB.Model(&ShoppingListMember{}).Select("shopping_list_id").Where("user_id = ?", userID)
+	if err := DB.Preload("Items").Preload("Members").
+		Where("owner_id = ? OR id IN (?)", userID, shared).Order("id").Find(&export.Shopping).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", userID).Order("id").Find(&export.Pantry).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("submitted_by = ?", userID).Order("id").Find(&export.Products).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", userID).Order("id").Find(&export.APIKeys).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Where("user_id = ?", userID).Order("id").Find(&export.Sessions).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Удалить аккаунт. Публичные блюда остаются в каталоге без автора,
+// чтобы не ломать подборки других пользователей
+func DeleteUser(userID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&DiaryEntry{}).Error; err != nil {
+			return err
+		}
+		plans := tx.Model(&MealPlan{}).Select("id").Where("user_id = ?", userID)
+		if err := tx.Where("meal_plan_id IN (?)", plans).Delete(&MealPlanEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&MealPlan{}).Error; err != nil {
+			return err
+		}
+
+		// Свои списки покупок удаляются вместе с позициями, в чужих остаются отметки без автора
+		lists := tx.Model(&ShoppingList{}).Select("id").Where("owner_id = ?", userID)
+		if err := tx.Where("shopping_list_id IN (?)", lists).Delete(&ShoppingListItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shopping_list_id IN (?) OR user_id = ?", lists, userID).Delete(&ShoppingListMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("owner_id = ?", userID).Delete(&ShoppingList{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&ShoppingListItem{}).Where("checked_by = ?", userID).Update("checked_by", nil).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Dish{}).Unscoped().
+			Where("user_id = ? AND is_public", userID).
+			Update("user_id", nil).Error; err != nil {
+			return err
+		}
+
+		private := tx.Model(&Dish{}).Unscoped().Select("id").Where("user_id = ?", userID)
+		if err := tx.Unscoped().Where("dish_id IN (?)", private).Delete(&Ingredient{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&Dish{}).Error; err != nil {
+			return err
+		}
+
+		// Продукты из каталога не удаляем, только убираем привязку к автору
+		if err := tx.Model(&Product{}).Where("submitted_by = ?", userID).Update("submitted_by", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Product{}).Where("reviewed_by = ?", userID).Update("reviewed_by", nil).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&Profile{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&PantryItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&WeightEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&ExerciseEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, userID).Error
+	})
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Права API-ключей
+type APIScope string
+
+const (
+	SCOPE_CATALOG_READ APIScope = "catalog:read"  // чтение каталога продуктов и блюд
+	SCOPE_NUTRITION    APIScope = "nutrition"     // расчет пищевой ценности
+	SCOPE_WRITE        APIScope = "catalog:write" // добавление продуктов и блюд
+)
+
+// Префикс, по которому ключи отличаются от других токенов
+const APIKeyPrefix = "fk_"
+
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     []APIScope `json:"scopes" gorm:"serializer:json;not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	UsageCount int64      `json:"usage_count" gorm:"not null;default:0"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func ValidateAPIScope(scope APIScope) error {
+	switch scope {
+	case SCOPE_CATALOG_READ, SCOPE_NUTRITION, SCOPE_WRITE:
+		return nil
+	default:
+		return errors.New("invalid api key scope")
+	}
+}
+
+// Создать новый ключ. Открытое значение возвращается только один раз
+func NewAPIKey(userID uint, name string, scopes []APIScope) (*APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("api key name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", errors.New("api key must have at least one scope")
+	}
+	for _, scope := range scopes {
+		if err := ValidateAPIScope(scope); err != nil {
+			return nil, "", err
+		}
+	}
+
+	key, err := newToken(APIKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  key[:len(APIKeyPrefix)+6],
+		KeyHash: HashToken(key),
+		Scopes:  scopes,
+	}, key, nil
+}
+
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil
+}
+
+func (k *APIKey) HasScope(scope APIScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
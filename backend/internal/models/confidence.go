@@ -0,0 +1,105 @@
+package models
+
+// Насколько можно доверять рассчитанной пищевой ценности
+type Confidence string
+
+const (
+	CONFIDENCE_HIGH   Confidence = "high"   // вес известен или задан для продукта
+	CONFIDENCE_MEDIUM Confidence = "medium" // плотность по категории
+	CONFIDENCE_LOW    Confidence = "low"    // средний вес по категории, плотность воды
+)
+
+var confidenceRank = map[Confidence]int{
+	CONFIDENCE_HIGH:   3,
+	CONFIDENCE_MEDIUM: 2,
+	CONFIDENCE_LOW:    1,
+}
+
+// Уверенность и относительная погрешность веса для способа перевода
+var conversionUncertainty = map[ConversionMethod]struct {
+	confidence Confidence
+	margin     float64
+}{
+	CONVERSION_EXACT:            {CONFIDENCE_HIGH, 0},
+	CONVERSION_UNIT_WEIGHT:      {CONFIDENCE_HIGH, 0.05},
+	CONVERSION_SERVING:          {CONFIDENCE_HIGH, 0.05},
+	CONVERSION_PRODUCT_DENSITY:  {CONFIDENCE_HIGH, 0.05},
+	CONVERSION_CATEGORY_DENSITY: {CONFIDENCE_MEDIUM, 0.15},
+	CONVERSION_CATEGORY_AVERAGE: {CONFIDENCE_LOW, 0.3},
+	CONVERSION_WATER_DENSITY:    {CONFIDENCE_LOW, 0.3},
+}
+
+func (m ConversionMethod) Confidence() Confidence {
+	return conversionUncertainty[m].confidence
+}
+
+// Менее надежная из двух оценок. Пустая оценка ничего не меняет
+func lowerConfidence(a, b Confidence) Confidence {
+	if a == "" || b != "" && confidenceRank[b] < confidenceRank[a] {
+		return b
+	}
+	return a
+}
+
+// Значения пищевой ценности без метаданных
+type NutritionValues struct {
+	Calories float64 `json:"calories"`
+	Fats     float64 `json:"fats"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Weight   float64 `json:"weight"`
+}
+
+func (v NutritionValues) add(other NutritionValues) NutritionValues {
+	return NutritionValues{
+		Calories: v.Calories + other.Calories,
+		Fats:     v.Fats + other.Fats,
+		Protein:  v.Protein + other.Protein,
+		Carbs:    v.Carbs + other.Carbs,
+		Weight:   v.Weight + other.Weight,
+	}
+}
+
+func (v NutritionValues) scale(factor float64) NutritionValues {
+	return NutritionValues{
+		Calories: v.Calories * factor,
+		Fats:     v.Fats * factor,
+		Protein:  v.Protein * factor,
+		Carbs:    v.Carbs * factor,
+		Weight:   v.Weight * factor,
+	}
+}
+
+// Границы, в которых лежит настоящее значение
+type NutritionRange struct {
+	Min NutritionValues `json:"min"`
+	Max NutritionValues `json:"max"`
+}
+
+func (n *NutritionInfo) values() NutritionValues {
+	return NutritionValues{
+		Calories: n.Calories,
+		Fats:     n.Fats,
+		Protein:  n.Protein,
+		Carbs:    n.Carbs,
+		Weight:   n.Weight,
+	}
+}
+
+// Границы значения; для точного значения обе границы совпадают с ним
+func (n *NutritionInfo) bounds() NutritionRange {
+	if n.Range != nil {
+		return *n.Range
+	}
+	return NutritionRange{Min: n.values(), Max: n.values()}
+}
+
+// Указать, каким способом вес был получен
+func (n *NutritionInfo) setConversion(method ConversionMethod) {
+	n.Confidence = method.Confidence()
+	n.WaterDensityUsed = method == CONVERSION_WATER_DENSITY
+	if margin := conversionUncertainty[method].margin; margin > 0 {
+		values := n.values()
+		n.Range = &NutritionRange{Min: values.scale(1 - margin), Max: values.scale(1 + margin)}
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// Календарная дата без времени, в JSON - "2006-01-02"
+type Date struct {
+	time.Time
+}
+
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func Today() Date {
+	return NewDate(time.Now())
+}
+
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, errors.New("invalid date, expected YYYY-MM-DD")
+	}
+	return Date{t}, nil
+}
+
+func (d Date) AddDays(days int) Date {
+	return Date{d.Time.AddDate(0, 0, days)}
+}
+
+// Количество дней от d до other
+func (d Date) DaysUntil(other Date) int {
+	return int(other.Time.Sub(d.Time).Hours() / 24)
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (Date) GormDataType() string {
+	return "date"
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Date) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		*d = NewDate(v)
+	case string:
+		parsed, err := ParseDate(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case []byte:
+		parsed, err := ParseDate(string(v))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	default:
+		return errors.New("cannot scan date")
+	}
+	return nil
+}
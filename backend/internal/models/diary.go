@@ -0,0 +1,200 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Прием пищи
+type MealSlot string
+
+const (
+	SLOT_BREAKFAST MealSlot = "breakfast" // завтрак
+	SLOT_LUNCH     MealSlot = "lunch"     // обед
+	SLOT_DINNER    MealSlot = "dinner"    // ужин
+	SLOT_SNACK     MealSlot = "snack"     // перекус
+)
+
+var MealSlots = []MealSlot{SLOT_BREAKFAST, SLOT_LUNCH, SLOT_DINNER, SLOT_SNACK}
+
+// Запись в дневнике питания: продукт в количестве Amount/Unit
+// либо блюдо в порциях (Servings) или граммах (Amount/Unit)
+type DiaryEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index:idx_diary_user_date"`
+	Date      Date      `json:"date" gorm:"not null;index:idx_diary_user_date"`
+	Slot      MealSlot  `json:"slot" gorm:"not null"`
+	ProductID *uint     `json:"product_id"`
+	Product   *Product  `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	DishID    *uint     `json:"dish_id"`
+	Dish      *Dish     `json:"dish,omitempty" gorm:"foreignKey:DishID"`
+	Amount    float64   `json:"amount"`
+	Unit      Unit      `json:"unit"`
+	Servings  float64   `json:"servings"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Итоги дня по дневнику
+type DailySummary struct {
+	Date    Date                       `json:"date"`
+	Total   NutritionInfo              `json:"total"`
+	BySlot  map[MealSlot]NutritionInfo `json:"by_slot"`
+	Skipped []uint                     `json:"skipped_entries"` // записи, для которых не удалось посчитать КБЖУ
+
+	// Энергобаланс с учетом тренировок
+	Burned      float64 `json:"burned_calories"`
+	NetCalories float64 `json:"net_calories"`
+
+	// Заполняются, если у пользователя есть профиль
+	Target    *NutritionInfo `json:"target,omitempty"`
+	Remaining *NutritionInfo `json:"remaining,omitempty"`
+}
+
+func ValidateMealSlot(slot MealSlot) error {
+	switch slot {
+	case SLOT_BREAKFAST, SLOT_LUNCH, SLOT_DINNER, SLOT_SNACK:
+		return nil
+	default:
+		return errors.New("invalid meal slot")
+	}
+}
+
+func (e *DiaryEntry) Validate() error {
+	if err := ValidateMealSlot(e.Slot); err != nil {
+		return err
+	}
+
+	switch {
+	case e.ProductID != nil && e.DishID != nil:
+		return errors.New("entry must reference either a product or a dish, not both")
+	case e.ProductID != nil:
+		if e.Amount <= 0 {
+			return errors.New("product entry requires a positive amount")
+		}
+		if e.Servings != 0 {
+			return errors.New("servings apply only to dish entries")
+		}
+	case e.DishID != nil:
+		if (e.Servings > 0) == (e.Amount > 0) {
+			return errors.New("dish entry requires either servings or amount")
+		}
+		if e.Servings < 0 || e.Amount < 0 {
+			return errors.New("dish entry amount must be positive")
+		}
+	default:
+		return errors.New("entry must reference a product or a dish")
+	}
+	return nil
+}
+
+// Рассчитать пищевую ценность записи. Product или Dish.Ingredients.Product
+// должны быть загружены
+func (e *DiaryEntry) Nutrition() (*NutritionInfo, error) {
+	if e.Product != nil {
+		return e.Product.CalculateNutrition(e.Amount, e.Unit)
+	}
+	if e.Dish == nil {
+		return nil, errors.New("diary entry has no product or dish")
+	}
+
+	if e.Servings > 0 {
+		total, err := e.Dish.CalculateTotalNutrition()
+		if err != nil {
+			return nil, err
+		}
+		nutrition := total.PerServing.Scale(e.Servings)
+		return &nutrition, nil
+	}
+
+	grams, err := e.Dish.convertToGrams(e.Amount, e.Unit)
+	if err != nil {
+		return nil, err
+	}
+	per100g, err := e.Dish.CalculateNutritionPer100g()
+	if err != nil {
+		return nil, err
+	}
+	nutrition := per100g.Scale(grams / 100.0)
+	return &nutrition, nil
+}
+
+// Сложить записи одного дня
+func SummarizeDiary(date Date, entries []DiaryEntry) *DailySummary {
+	summary := &DailySummary{
+		Date:    date,
+		BySlot:  map[MealSlot]NutritionInfo{},
+		Skipped: []uint{},
+	}
+	for _, slot := range MealSlots {
+		summary.BySlot[slot] = NutritionInfo{}
+	}
+
+	for _, entry := range entries {
+		nutrition, err := entry.Nutrition()
+		if err != nil {
+			summary.Skipped = append(summary.Skipped, entry.ID)
+			continue
+		}
+
+		slot := summary.BySlot[entry.Slot]
+		slot.Add(*nutrition)
+		summary.BySlot[entry.Slot] = slot
+		summary.Total.Add(*nutrition)
+	}
+	summary.NetCalories = summary.Total.Calories
+	return summary
+}
+
+// Учесть сожженные на тренировках калории
+func (s *DailySummary) ApplyExercise(entries []ExerciseEntry) {
+	for _, entry := range entries {
+		s.Burned += entry.Calories
+	}
+	s.NetCalories = s.Total.Calories - s.Burned
+}
+
+// Сравнить съеденное с дневной нормой. Калории тренировок
+// увеличивают остаток, поэтому ApplyExercise вызывается раньше
+func (s *DailySummary) ApplyTargets(targets *MacroTargets) {
+	target := targets.Nutrition()
+	remaining := target
+	remaining.Add(s.Total.Scale(-1))
+	burned := s.Total.Calories - s.NetCalories
+	remaining.Calories += burned
+	remaining.Weight = 0
+	if remaining.Range != nil {
+		remaining.Range.Min.Calories += burned
+		remaining.Range.Max.Calories += burned
+		remaining.Range.Min.Weight, remaining.Range.Max.Weight = 0, 0
+	}
+
+	s.Target = &target
+	s.Remaining = &remaining
+}
+
+// Какую долю рецепта составляет запись с блюдом. Dish.Ingredients.Product
+// должны быть загружены
+func (e *DiaryEntry) DishFactor() (float64, error) {
+	if e.Dish == nil {
+		return 0, errors.New("diary entry has no dish")
+	}
+	if e.Servings > 0 {
+		if e.Dish.Servings <= 0 {
+			return e.Servings, nil
+		}
+		return e.Servings / float64(e.Dish.Servings), nil
+	}
+
+	grams, err := e.Dish.convertToGrams(e.Amount, e.Unit)
+	if err != nil {
+		return 0, err
+	}
+	total, err := e.Dish.CalculateTotalNutrition()
+	if err != nil {
+		return 0, err
+	}
+	if total.CookedWeight == 0 {
+		return 0, errors.New("cannot calculate dish share: total weight is zero")
+	}
+	return grams / total.CookedWeight, nil
+}
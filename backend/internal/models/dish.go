@@ -32,9 +32,14 @@ type Ingredient struct {
 	Unit      Unit    `gorm:"not null;default:'g'"`
 
 	// Кулинарная обработка
-	Preparation string `gorm:"comment:'способ подготовки'"`
-	IsOptional  bool   `gorm:"default:false"`
-	Notes       string `gorm:"comment:'заметки пользователя'"`
+	Preparation string      `gorm:"comment:'способ подготовки'"`
+	YieldFactor *float64    `gorm:"check:yield_factor > 0;comment:'выход после готовки, важнее таблицы'"`
+	AmountBasis AmountBasis `gorm:"not null;default:'edible';comment:'количество съедобной части или как куплено'"`
+	IsOptional  bool        `gorm:"default:false"`
+	Notes       string      `gorm:"comment:'заметки пользователя'"`
+
+	// Количество в системе единиц пользователя, не хранится
+	Display *DisplayAmount `gorm:"-"`
 }
 
 type Dish struct {
@@ -50,6 +55,9 @@ type Dish struct {
 	Servings        int    `gorm:"default:1;check:servings > 0"`
 	Instructions    string `gorm:"type:text"`
 
+	// Измеренный вес готового блюда; если не задан, считается по выходу ингредиентов
+	CookedWeight *float64 `gorm:"check:cooked_weight > 0;comment:'вес готового блюда в граммах'"`
+
 	// Связи
 	Ingredients []Ingredient `gorm:"foreignKey:DishID"`
 
@@ -65,8 +73,19 @@ type DishNutrition struct {
 	TotalFats     float64
 	TotalProtein  float64
 	TotalCarbs    float64
-	TotalWeight   float64
+	TotalWeight   float64 // сырой вес ингредиентов
+	CookedWeight  float64 // вес готового блюда
 	PerServing    NutritionInfo
+
+	// Вес готового блюда измерен, а не рассчитан по выходу
+	CookedWeightMeasured bool
+
+	// Хотя бы один ингредиент переведен из объема по плотности воды
+	WaterDensityUsed bool
+
+	// Самая низкая уверенность среди ингредиентов и границы итогов
+	Confidence Confidence
+	Range      *NutritionRange
 }
 
 // Валидация категории блюда
@@ -88,6 +107,14 @@ func (d *Dish) IsQuickMeal() bool {
 	return d.TotalTime() <= 30 // быстрое блюдо - до 30 минут
 }
 
+// Публичные и общие блюда видны всем, личные - только автору
+func (d *Dish) IsVisibleTo(user *User) bool {
+	if d.UserID == nil || d.IsPublic {
+		return true
+	}
+	return user != nil && *d.UserID == user.ID
+}
+
 func (d *Dish) HasInstructions() bool {
 	return d.Instructions != ""
 }
@@ -98,7 +125,8 @@ func (d *Dish) CalculateTotalNutrition() (*DishNutrition, error) {
 		return nil, errors.New("dish has no ingredients")
 	}
 
-	totalNutrition := &DishNutrition{}
+	total := NutritionInfo{}
+	cookedWeight := 0.0
 
 	for _, ingredient := range d.Ingredients {
 		// Пропускаем опциональные ингредиенты без пищевой ценности
@@ -106,53 +134,92 @@ func (d *Dish) CalculateTotalNutrition() (*DishNutrition, error) {
 			continue
 		}
 
-		nutrition, err := ingredient.Product.CalculateNutrition(ingredient.Amount, ingredient.Unit)
+		nutrition, err := ingredient.Nutrition()
 		if err != nil {
 			// Если не можем рассчитать для какого-то ингредиента, пропускаем
 			continue
 		}
+		total.Add(*nutrition)
+		cookedWeight += nutrition.Weight * ingredient.yieldFactor()
+	}
+	if d.CookedWeight != nil {
+		cookedWeight = *d.CookedWeight
+	}
 
-		totalNutrition.TotalCalories += nutrition.Calories
-		totalNutrition.TotalFats += nutrition.Fats
-		totalNutrition.TotalProtein += nutrition.Protein
-		totalNutrition.TotalCarbs += nutrition.Carbs
-		totalNutrition.TotalWeight += nutrition.Weight
+	totalNutrition := &DishNutrition{
+		TotalCalories:    total.Calories,
+		TotalFats:        total.Fats,
+		TotalProtein:     total.Protein,
+		TotalCarbs:       total.Carbs,
+		TotalWeight:      total.Weight,
+		CookedWeight:     cookedWeight,
+		WaterDensityUsed: total.WaterDensityUsed,
+		Confidence:       total.Confidence,
+		Range:            total.Range,
+
+		CookedWeightMeasured: d.CookedWeight != nil,
+	}
+
+	// Границы веса тоже относятся к готовому блюду
+	if total.Range != nil && total.Weight > 0 {
+		yield := cookedWeight / total.Weight
+		total.Range.Min.Weight *= yield
+		total.Range.Max.Weight *= yield
 	}
 
 	// Рассчитываем на порцию
 	if d.Servings > 0 {
-		totalNutrition.PerServing = NutritionInfo{
-			Calories: totalNutrition.TotalCalories / float64(d.Servings),
-			Fats:     totalNutrition.TotalFats / float64(d.Servings),
-			Protein:  totalNutrition.TotalProtein / float64(d.Servings),
-			Carbs:    totalNutrition.TotalCarbs / float64(d.Servings),
-			Weight:   totalNutrition.TotalWeight / float64(d.Servings),
-		}
+		totalNutrition.PerServing = totalNutrition.Total().Scale(1 / float64(d.Servings))
 	}
 
 	return totalNutrition, nil
 }
 
-// Рассчитать пищевую ценность на 100г готового блюда
+// Пищевая ценность всего блюда в том весе, в котором его подают
+func (n *DishNutrition) Total() NutritionInfo {
+	return NutritionInfo{
+		Calories: n.TotalCalories,
+		Fats:     n.TotalFats,
+		Protein:  n.TotalProtein,
+		Carbs:    n.TotalCarbs,
+		Weight:   n.CookedWeight,
+
+		WaterDensityUsed: n.WaterDensityUsed,
+		Confidence:       n.Confidence,
+		Range:            n.Range,
+	}
+}
+
+// Рассчитать пищевую ценность на 100г готового блюда с учетом
+// изменения веса при готовке
 func (d *Dish) CalculateNutritionPer100g() (*NutritionInfo, error) {
 	totalNutrition, err := d.CalculateTotalNutrition()
 	if err != nil {
 		return nil, err
 	}
 
-	if totalNutrition.TotalWeight == 0 {
+	if totalNutrition.CookedWeight == 0 {
 		return nil, errors.New("cannot calculate nutrition per 100g: total weight is zero")
 	}
 
-	factor := 100.0 / totalNutrition.TotalWeight
+	nutrition := totalNutrition.Total().Scale(100.0 / totalNutrition.CookedWeight)
+	if nutrition.Range != nil {
+		nutrition.Range.Min.Weight, nutrition.Range.Max.Weight = 100.0, 100.0
+	}
+	return &nutrition, nil
+}
 
-	return &NutritionInfo{
-		Calories: totalNutrition.TotalCalories * factor,
-		Fats:     totalNutrition.TotalFats * factor,
-		Protein:  totalNutrition.TotalProtein * factor,
-		Carbs:    totalNutrition.TotalCarbs * factor,
-		Weight:   100.0,
-	}, nil
+// Перевести количество готового блюда в граммы. Для блюда
+// известны только весовые единицы
+func (d *Dish) convertToGrams(amount float64, unit Unit) (float64, error) {
+	switch unit {
+	case GRAM, "":
+		return amount, nil
+	case KILOGRAM:
+		return amount * massUnitGrams[KILOGRAM], nil
+	default:
+		return 0, errors.New("dish amount must be given in grams or kilograms")
+	}
 }
 
 // Найти ингредиенты определенной категории
@@ -265,8 +332,8 @@ func (d *Dish) EstimateCost(productPrices map[uint]float64) (float64, error) {
 
 	for _, ingredient := range d.Ingredients {
 		if price, exists := productPrices[ingredient.ProductID]; exists {
-			// Примерный расчет стоимости на основе веса
-			weightInGrams, err := ingredient.Product.convertToGrams(ingredient.Amount, ingredient.Unit)
+			// Примерный расчет стоимости на основе веса, упаковки - целиком
+			weightInGrams, err := ingredient.Product.purchaseGrams(ingredient.PurchasedAmount(), ingredient.Unit)
 			if err != nil {
 				continue // пропускаем ингредиенты с неизвестным весом
 			}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Система единиц, в которой пользователь видит количества
+type UnitSystem string
+
+const (
+	METRIC       UnitSystem = "metric" // граммы и миллилитры
+	US_CUSTOMARY UnitSystem = "us"     // унции, стаканы и ложки
+)
+
+func ValidateUnitSystem(system UnitSystem) error {
+	switch system {
+	case METRIC, US_CUSTOMARY:
+		return nil
+	default:
+		return errors.New("invalid unit system")
+	}
+}
+
+// Количество для показа пользователю. Хранимые значения не меняются
+type DisplayAmount struct {
+	Amount float64 `json:"amount"`
+	Unit   Unit    `json:"unit"`
+	Text   string  `json:"text"` // "1 1/2 cup"
+}
+
+// Продукты, которые в американских рецептах отмеряют ложками и стаканами,
+// а не взвешивают
+var volumeMeasuredCategories = map[ProductCategory]bool{
+	OIL:      true,
+	DAIRY:    true,
+	SAUCE:    true,
+	BEVERAGE: true,
+	GRAIN:    true,
+	SPICE:    true,
+	SWEET:    true,
+}
+
+// Метрические единицы показываются десятичными числами, остальные - дробями
+var decimalUnits = map[Unit]bool{
+	GRAM:       true,
+	KILOGRAM:   true,
+	MILLILITER: true,
+	LITER:      true,
+}
+
+// Количество продукта в выбранной системе единиц. Штуки, порции
+// и упаковки показываются как есть
+func (p *Product) DisplayAmount(amount float64, unit Unit, system UnitSystem) DisplayAmount {
+	if system == US_CUSTOMARY {
+		if display, ok := p.usAmount(amount, unit); ok {
+			return display
+		}
+	} else if display, ok := metricAmount(amount, unit); ok {
+		return display
+	}
+	return newDisplayAmount(amount, unit)
+}
+
+// Количества ингредиентов для показа в выбранной системе единиц.
+// Ingredients.Product должны быть загружены
+func (d *Dish) LocalizeAmounts(system UnitSystem) {
+	for i := range d.Ingredients {
+		ingredient := &d.Ingredients[i]
+		display := ingredient.Product.DisplayAmount(ingredient.Amount, ingredient.Unit, system)
+		ingredient.Display = &display
+	}
+}
+
+func metricAmount(amount float64, unit Unit) (DisplayAmount, bool) {
+	switch unit {
+	case OUNCE, POUND:
+		grams := amount * massUnitGrams[unit]
+		if grams >= 1000 {
+			return newDisplayAmount(grams/1000, KILOGRAM), true
+		}
+		return newDisplayAmount(grams, GRAM), true
+	case FLUID_OUNCE, PINT, QUART:
+		ml := amount * volumeUnitMilliliters[unit]
+		if ml >= 1000 {
+			return newDisplayAmount(ml/1000, LITER), true
+		}
+		return newDisplayAmount(ml, MILLILITER), true
+	}
+	return DisplayAmount{}, false
+}
+
+func (p *Product) usAmount(amount float64, unit Unit) (DisplayAmount, bool) {
+	switch unit {
+	case GRAM, KILOGRAM:
+		grams := amount * massUnitGrams[unit]
+		if volumeMeasuredCategories[p.Category] {
+			density, _ := p.density()
+			return usVolume(grams / density), true
+		}
+		return usMass(grams), true
+	case MILLILITER, LITER:
+		return usVolume(amount * volumeUnitMilliliters[unit]), true
+	}
+	return DisplayAmount{}, false
+}
+
+// Объем в самой удобной единице: стаканы, столовые или чайные ложки
+func usVolume(ml float64) DisplayAmount {
+	cups := ml / volumeUnitMilliliters[CUP]
+	switch {
+	case cups >= 4:
+		return newDisplayAmount(roundToFraction(ml/volumeUnitMilliliters[QUART], 4), QUART)
+	case cups >= 0.25:
+		return newDisplayAmount(roundToFraction(cups, 3, 4), CUP)
+	}
+
+	tablespoons := ml / volumeUnitMilliliters[TABLESPOON]
+	if tablespoons >= 1 {
+		return newDisplayAmount(roundToFraction(tablespoons, 2), TABLESPOON)
+	}
+	teaspoons := ml / volumeUnitMilliliters[TEASPOON]
+	return newDisplayAmount(math.Max(roundToFraction(teaspoons, 8), 0.125), TEASPOON)
+}
+
+// Вес в унциях, от фунта - в фунтах
+func usMass(grams float64) DisplayAmount {
+	ounces := grams / massUnitGrams[OUNCE]
+	if ounces >= 16 {
+		return newDisplayAmount(roundToFraction(grams/massUnitGrams[POUND], 4), POUND)
+	}
+	return newDisplayAmount(math.Max(roundToFraction(ounces, 4), 0.25), OUNCE)
+}
+
+func newDisplayAmount(amount float64, unit Unit) DisplayAmount {
+	if decimalUnits[unit] {
+		amount = RoundAmount(amount, unit)
+		return DisplayAmount{
+			Amount: amount,
+			Unit:   unit,
+			Text:   strconv.FormatFloat(amount, 'f', -1, 64) + " " + string(unit),
+		}
+	}
+	return DisplayAmount{
+		Amount: math.Round(amount*1000) / 1000,
+		Unit:   unit,
+		Text:   FormatFraction(amount) + " " + string(unit),
+	}
+}
+
+// Округлить до ближайшей дроби с одним из знаменателей
+func roundToFraction(value float64, denominators ...int) float64 {
+	best := math.Round(value)
+	for _, d := range denominators {
+		candidate := math.Round(value*float64(d)) / float64(d)
+		if math.Abs(candidate-value) < math.Abs(best-value) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// Записать число дробью: 1.5 -> "1 1/2", 0.333 -> "1/3". Если подходящей
+// дроби нет, число выводится с двумя знаками после запятой
+func FormatFraction(value float64) string {
+	whole := math.Floor(value)
+	fraction := value - whole
+	if fraction < 0.01 {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	if fraction > 0.99 {
+		return strconv.FormatFloat(whole+1, 'f', -1, 64)
+	}
+
+	for _, d := range []int{2, 3, 4, 8} {
+		n := math.Round(fraction * float64(d))
+		if math.Abs(fraction*float64(d)-n) < 0.02 {
+			if whole == 0 {
+				return fmt.Sprintf("%.0f/%d", n, d)
+			}
+			return fmt.Sprintf("%.0f %.0f/%d", whole, n, d)
+		}
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Вид активности из справочника с метаболическим эквивалентом (MET)
+type Activity struct {
+	ID   uint    `json:"id" gorm:"primaryKey"`
+	Name string  `json:"name" gorm:"not null;uniqueIndex"`
+	MET  float64 `json:"met" gorm:"not null;check:met > 0"`
+}
+
+// Базовый справочник (Compendium of Physical Activities)
+var defaultActivities = []Activity{
+	{Name: "walking, 5 km/h", MET: 3.5},
+	{Name: "walking, 6.5 km/h", MET: 5.0},
+	{Name: "running, 8 km/h", MET: 8.3},
+	{Name: "running, 10 km/h", MET: 9.8},
+	{Name: "running, 12 km/h", MET: 11.8},
+	{Name: "cycling, leisure", MET: 4.0},
+	{Name: "cycling, moderate", MET: 8.0},
+	{Name: "swimming, freestyle moderate", MET: 5.8},
+	{Name: "strength training", MET: 5.0},
+	{Name: "yoga", MET: 2.5},
+	{Name: "hiking", MET: 6.0},
+	{Name: "dancing", MET: 5.0},
+	{Name: "football", MET: 7.0},
+	{Name: "tennis", MET: 7.3},
+	{Name: "rowing machine, moderate", MET: 7.0},
+	{Name: "jump rope", MET: 11.8},
+}
+
+// Запись о тренировке. Сожженные калории считаются при сохранении
+type ExerciseEntry struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index:idx_exercise_user_date"`
+	Date        Date      `json:"date" gorm:"not null;index:idx_exercise_user_date"`
+	ActivityID  uint      `json:"activity_id" gorm:"not null"`
+	Activity    *Activity `json:"activity,omitempty" gorm:"foreignKey:ActivityID"`
+	DurationMin float64   `json:"duration_min" gorm:"not null;check:duration_min > 0"`
+	WeightKg    float64   `json:"weight_kg" gorm:"not null"` // вес тела на момент тренировки
+	Calories    float64   `json:"calories" gorm:"not null"`
+	Notes       string    `json:"notes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Заполнить справочник активностей, не трогая уже существующие
+func seedActivities(db *gorm.DB) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaultActivities).Error
+}
+
+func (a *Activity) Validate() error {
+	if strings.TrimSpace(a.Name) == "" {
+		return errors.New("activity name is required")
+	}
+	if a.MET <= 0 || a.MET > 25 {
+		return errors.New("met must be between 0 and 25")
+	}
+	return nil
+}
+
+// Сожженные калории: MET * вес (кг) * время (ч)
+func CaloriesBurned(met, weightKg, durationMin float64) float64 {
+	return math.Round(met*weightKg*durationMin/60*10) / 10
+}
+
+// Проверить запись и посчитать калории. Activity должна быть загружена
+func (e *ExerciseEntry) Calculate() error {
+	if e.Activity == nil {
+		return errors.New("exercise entry has no activity")
+	}
+	if e.DurationMin <= 0 || e.DurationMin > 24*60 {
+		return errors.New("duration must be between 0 and 1440 minutes")
+	}
+	if e.WeightKg <= 0 {
+		return errors.New("body weight is required to calculate burned calories")
+	}
+	e.Calories = CaloriesBurned(e.Activity.MET, e.WeightKg, e.DurationMin)
+	return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// План питания, который пользователь собирает вручную в календаре
+type MealPlan struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	UserID    uint            `json:"user_id" gorm:"not null;index"`
+	Name      string          `json:"name" gorm:"not null"`
+	Entries   []MealPlanEntry `json:"entries,omitempty" gorm:"foreignKey:MealPlanID"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type MealPlanEntry struct {
+	ID         uint     `json:"id" gorm:"primaryKey"`
+	MealPlanID uint     `json:"meal_plan_id" gorm:"not null;index:idx_meal_plan_date"`
+	Date       Date     `json:"date" gorm:"not null;index:idx_meal_plan_date"`
+	Slot       MealSlot `json:"slot" gorm:"not null"`
+	DishID     uint     `json:"dish_id" gorm:"not null"`
+	Dish       *Dish    `json:"dish,omitempty" gorm:"foreignKey:DishID"`
+	Servings   float64  `json:"servings" gorm:"not null;default:1;check:servings > 0"`
+}
+
+// Итоги плана за день
+type PlanDayNutrition struct {
+	Date    Date          `json:"date"`
+	Total   NutritionInfo `json:"total"`
+	Skipped []uint        `json:"skipped_entries"`
+}
+
+// Итоги плана за неделю
+type PlanWeekNutrition struct {
+	WeekStart Date               `json:"week_start"`
+	Days      []PlanDayNutrition `json:"days"`
+	Total     NutritionInfo      `json:"total"`
+	Average   NutritionInfo      `json:"daily_average"`
+}
+
+func (p *MealPlan) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("meal plan name is required")
+	}
+	return nil
+}
+
+func (e *MealPlanEntry) Validate() error {
+	if err := ValidateMealSlot(e.Slot); err != nil {
+		return err
+	}
+	if e.Servings <= 0 {
+		return errors.New("servings must be positive")
+	}
+	return nil
+}
+
+// Пищевая ценность записи. Dish.Ingredients.Product должны быть загружены
+func (e *MealPlanEntry) Nutrition() (*NutritionInfo, error) {
+	if e.Dish == nil {
+		return nil, errors.New("meal plan entry has no dish")
+	}
+	total, err := e.Dish.CalculateTotalNutrition()
+	if err != nil {
+		return nil, err
+	}
+	nutrition := total.PerServing.Scale(e.Servings)
+	return &nutrition, nil
+}
+
+// Понедельник недели, в которую входит дата
+func WeekStart(d Date) Date {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDays(-offset)
+}
+
+// Сложить записи плана по дням недели, начиная с weekStart
+func SummarizeMealPlanWeek(weekStart Date, entries []MealPlanEntry) *PlanWeekNutrition {
+	week := &PlanWeekNutrition{WeekStart: weekStart}
+	for i := 0; i < 7; i++ {
+		week.Days = append(week.Days, PlanDayNutrition{Date: weekStart.AddDays(i), Skipped: []uint{}})
+	}
+
+	for _, entry := range entries {
+		day := weekStart.DaysUntil(entry.Date)
+		if day < 0 || day >= 7 {
+			continue
+		}
+		nutrition, err := entry.Nutrition()
+		if err != nil {
+			week.Days[day].Skipped = append(week.Days[day].Skipped, entry.ID)
+			continue
+		}
+		week.Days[day].Total.Add(*nutrition)
+		week.Total.Add(*nutrition)
+	}
+	week.Average = week.Total.Scale(1.0 / 7)
+	return week
+}
+
+// Копии записей, сдвинутые на days дней
+func ShiftMealPlanEntries(entries []MealPlanEntry, days int) []MealPlanEntry {
+	shifted := make([]MealPlanEntry, 0, len(entries))
+	for _, entry := range entries {
+		shifted = append(shifted, MealPlanEntry{
+			MealPlanID: entry.MealPlanID,
+			Date:       entry.Date.AddDays(days),
+			Slot:       entry.Slot,
+			DishID:     entry.DishID,
+			Servings:   entry.Servings,
+		})
+	}
+	return shifted
+}
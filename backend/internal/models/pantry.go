@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Продукт в домашних запасах пользователя
+type PantryItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ProductID uint      `json:"product_id" gorm:"not null;index"`
+	Product   *Product  `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Quantity  float64   `json:"quantity" gorm:"not null;check:quantity >= 0"`
+	Unit      Unit      `json:"unit" gorm:"not null;default:'g'"`
+	ExpiresAt *Date     `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (i *PantryItem) Validate() error {
+	if i.Quantity <= 0 {
+		return errors.New("quantity must be positive")
+	}
+	return ValidateUnit(i.Unit)
+}
+
+// Истекает ли срок годности не позже указанной даты
+func (i *PantryItem) ExpiresBy(date Date) bool {
+	return i.ExpiresAt != nil && !i.ExpiresAt.After(date.Time)
+}
+
+// Сколько единиц unit в одной единице позиции. ok = false, если единицы несовместимы
+func (i *PantryItem) unitRatio(product *Product, unit Unit) (float64, bool) {
+	if i.Unit == unit {
+		return 1, true
+	}
+	itemGrams, err := product.convertToGrams(1, i.Unit)
+	if err != nil || itemGrams <= 0 {
+		return 0, false
+	}
+	unitGrams, err := product.convertToGrams(1, unit)
+	if err != nil || unitGrams <= 0 {
+		return 0, false
+	}
+	return itemGrams / unitGrams, true
+}
+
+// Списать продукт из запасов, начиная с позиций, которые испортятся раньше.
+// Изменяет items и возвращает индексы затронутых позиций и остаток,
+// которого в запасах не хватило
+func ConsumePantry(items []PantryItem, product *Product, amount float64, unit Unit) ([]int, float64) {
+	var order []int
+	for i := range items {
+		if items[i].ProductID == product.ID && items[i].Quantity > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ea, eb := items[order[a]].ExpiresAt, items[order[b]].ExpiresAt
+		if ea == nil || eb == nil {
+			return ea != nil
+		}
+		return ea.Before(eb.Time)
+	})
+
+	var touched []int
+	need := amount
+	for _, i := range order {
+		if need <= 0 {
+			break
+		}
+		ratio, ok := items[i].unitRatio(product, unit)
+		if !ok {
+			continue
+		}
+
+		available := items[i].Quantity * ratio
+		take := min(need, available)
+		items[i].Quantity -= take / ratio
+		if items[i].Quantity < 1e-9 {
+			items[i].Quantity = 0
+		}
+		need -= take
+		touched = append(touched, i)
+	}
+	return touched, need
+}
+
+// Запасы в виде количеств для вычитания из списка покупок
+func PantryAmounts(items []PantryItem) []ProductAmount {
+	amounts := make([]ProductAmount, 0, len(items))
+	for _, item := range items {
+		amounts = append(amounts, ProductAmount{ProductID: item.ProductID, Amount: item.Quantity, Unit: item.Unit})
+	}
+	return amounts
+}
@@ -0,0 +1,272 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// Дней в генерируемом плане
+const PlanDays = 7
+
+// Доля дневной калорийности на прием пищи
+var slotCalorieShare = map[MealSlot]float64{
+	SLOT_BREAKFAST: 0.25,
+	SLOT_LUNCH:     0.35,
+	SLOT_DINNER:    0.30,
+	SLOT_SNACK:     0.10,
+}
+
+// Какие категории блюд подходят для приема пищи
+var slotDishCategories = map[MealSlot][]DishCategory{
+	SLOT_BREAKFAST: {BREAKFAST, SANDWICH, DRINK},
+	SLOT_LUNCH:     {SOUP, SALAD, MAIN, PASTA, SANDWICH, WRAP, BURGER, PIZZA},
+	SLOT_DINNER:    {MAIN, SALAD, PASTA, WRAP, PIZZA, BURGER, SOUP},
+	SLOT_SNACK:     {DESSERT, DRINK, SALAD, SANDWICH},
+}
+
+// Границы и шаг масштабирования порций
+const (
+	minPlanServings  = 0.5
+	maxPlanServings  = 3.0
+	planServingsStep = 0.25
+)
+
+// Попыток подобрать блюда на один день
+const planAttemptsPerDay = 200
+
+// Штраф за повтор блюда в течение недели
+const planRepeatPenalty = 0.15
+
+type DietRestrictions struct {
+	Vegetarian bool `json:"vegetarian"`
+	Vegan      bool `json:"vegan"`
+	GlutenFree bool `json:"gluten_free"`
+}
+
+type PlannedMeal struct {
+	Day       int           `json:"day"` // 0..6 от начальной даты
+	Slot      MealSlot      `json:"slot"`
+	DishID    uint          `json:"dish_id"`
+	DishName  string        `json:"dish_name"`
+	Servings  float64       `json:"servings"`
+	Nutrition NutritionInfo `json:"nutrition"`
+	Locked    bool          `json:"locked"`
+}
+
+type PlanDay struct {
+	Day             int           `json:"day"`
+	Date            Date          `json:"date"`
+	Meals           []PlannedMeal `json:"meals"`
+	Total           NutritionInfo `json:"total"`
+	WithinTolerance bool          `json:"within_tolerance"`
+}
+
+type GeneratedPlan struct {
+	Seed      int64         `json:"seed"`
+	StartDate Date          `json:"start_date"`
+	Targets   NutritionInfo `json:"targets"`
+	Tolerance float64       `json:"tolerance"`
+	Days      []PlanDay     `json:"days"`
+}
+
+type PlanOptions struct {
+	Targets      NutritionInfo
+	Restrictions DietRestrictions
+	Tolerance    float64 // допустимое относительное отклонение, например 0.1
+	Seed         int64
+	StartDate    Date
+	Locked       []PlannedMeal // зафиксированные пользователем приемы пищи
+}
+
+// Блюдо из пула с заранее посчитанной ценностью одной порции
+type planCandidate struct {
+	dish       *Dish
+	perServing NutritionInfo
+}
+
+func (r DietRestrictions) Allows(d *Dish) bool {
+	if r.Vegan && !d.IsVegan() {
+		return false
+	}
+	if r.Vegetarian && !d.IsVegetarian() {
+		return false
+	}
+	if r.GlutenFree && !d.IsGlutenFree() {
+		return false
+	}
+	return true
+}
+
+// Сгенерировать план на неделю. Блюда должны быть загружены с Ingredients.Product.
+// При одинаковых seed и входных данных результат одинаковый
+func GenerateMealPlan(dishes []Dish, opts PlanOptions) (*GeneratedPlan, error) {
+	if opts.Targets.Calories <= 0 {
+		return nil, errors.New("calorie target must be positive")
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 0.1
+	}
+
+	all := map[uint]planCandidate{}
+	bySlot := map[MealSlot][]planCandidate{}
+	for i := range dishes {
+		d := &dishes[i]
+		nutrition, err := d.CalculateTotalNutrition()
+		if err != nil || nutrition.PerServing.Calories <= 0 {
+			continue
+		}
+		candidate := planCandidate{dish: d, perServing: nutrition.PerServing}
+		all[d.ID] = candidate
+
+		if !opts.Restrictions.Allows(d) {
+			continue
+		}
+		for _, slot := range MealSlots {
+			for _, category := range slotDishCategories[slot] {
+				if d.Category == category {
+					bySlot[slot] = append(bySlot[slot], candidate)
+					break
+				}
+			}
+		}
+	}
+
+	// Если для приема пищи нет подходящих по категории блюд, берем любые разрешенные
+	var allowed []planCandidate
+	for i := range dishes {
+		if c, ok := all[dishes[i].ID]; ok && opts.Restrictions.Allows(c.dish) {
+			allowed = append(allowed, c)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, errors.New("no dishes with nutrition information match the restrictions")
+	}
+	for _, slot := range MealSlots {
+		if len(bySlot[slot]) == 0 {
+			bySlot[slot] = allowed
+		}
+	}
+
+	locked := map[int]map[MealSlot]PlannedMeal{}
+	for _, meal := range opts.Locked {
+		if meal.Day < 0 || meal.Day >= PlanDays {
+			return nil, errors.New("locked meal day must be between 0 and 6")
+		}
+		if err := ValidateMealSlot(meal.Slot); err != nil {
+			return nil, err
+		}
+		candidate, ok := all[meal.DishID]
+		if !ok {
+			return nil, errors.New("locked dish not found or has no nutrition information")
+		}
+		if !opts.Restrictions.Allows(candidate.dish) {
+			return nil, errors.New("locked dish does not match the restrictions")
+		}
+		if meal.Servings <= 0 {
+			meal.Servings = 1
+		}
+		if locked[meal.Day] == nil {
+			locked[meal.Day] = map[MealSlot]PlannedMeal{}
+		}
+		locked[meal.Day][meal.Slot] = newPlannedMeal(meal.Day, meal.Slot, candidate, meal.Servings, true)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	plan := &GeneratedPlan{
+		Seed:      opts.Seed,
+		StartDate: opts.StartDate,
+		Targets:   opts.Targets,
+		Tolerance: opts.Tolerance,
+	}
+	used := map[uint]int{}
+
+	for day := 0; day < PlanDays; day++ {
+		var best []PlannedMeal
+		bestScore := math.Inf(1)
+
+		for attempt := 0; attempt < planAttemptsPerDay; attempt++ {
+			meals := make([]PlannedMeal, 0, len(MealSlots))
+			for _, slot := range MealSlots {
+				if meal, ok := locked[day][slot]; ok {
+					meals = append(meals, meal)
+					continue
+				}
+				pool := bySlot[slot]
+				candidate := pool[rng.Intn(len(pool))]
+				servings := scaleServings(opts.Targets.Calories*slotCalorieShare[slot], candidate.perServing.Calories)
+				meals = append(meals, newPlannedMeal(day, slot, candidate, servings, false))
+			}
+
+			score := scorePlanDay(meals, opts.Targets, used)
+			if score < bestScore {
+				best, bestScore = meals, score
+			}
+		}
+
+		planDay := PlanDay{Day: day, Date: opts.StartDate.AddDays(day), Meals: best}
+		for _, meal := range best {
+			planDay.Total.Add(meal.Nutrition)
+			used[meal.DishID]++
+		}
+		planDay.WithinTolerance = withinTolerance(planDay.Total, opts.Targets, opts.Tolerance)
+		plan.Days = append(plan.Days, planDay)
+	}
+	return plan, nil
+}
+
+func newPlannedMeal(day int, slot MealSlot, c planCandidate, servings float64, locked bool) PlannedMeal {
+	return PlannedMeal{
+		Day:       day,
+		Slot:      slot,
+		DishID:    c.dish.ID,
+		DishName:  c.dish.Name,
+		Servings:  servings,
+		Nutrition: c.perServing.Scale(servings),
+		Locked:    locked,
+	}
+}
+
+// Порций, чтобы попасть в калорийность приема пищи, с округлением до шага
+func scaleServings(targetCalories, perServingCalories float64) float64 {
+	servings := math.Round(targetCalories/perServingCalories/planServingsStep) * planServingsStep
+	return math.Min(math.Max(servings, minPlanServings), maxPlanServings)
+}
+
+// Отклонение дня от норм. Калории важнее БЖУ, повторы блюд штрафуются
+func scorePlanDay(meals []PlannedMeal, targets NutritionInfo, used map[uint]int) float64 {
+	total := NutritionInfo{}
+	score := 0.0
+	seen := map[uint]bool{}
+	for _, meal := range meals {
+		total.Add(meal.Nutrition)
+		if !meal.Locked {
+			score += float64(used[meal.DishID]) * planRepeatPenalty
+			if seen[meal.DishID] {
+				score += planRepeatPenalty * 2
+			}
+		}
+		seen[meal.DishID] = true
+	}
+
+	score += 2 * relativeError(total.Calories, targets.Calories)
+	score += relativeError(total.Protein, targets.Protein)
+	score += relativeError(total.Fats, targets.Fats)
+	score += relativeError(total.Carbs, targets.Carbs)
+	return score
+}
+
+func withinTolerance(total, targets NutritionInfo, tolerance float64) bool {
+	return relativeError(total.Calories, targets.Calories) <= tolerance &&
+		relativeError(total.Protein, targets.Protein) <= tolerance*2 &&
+		relativeError(total.Fats, targets.Fats) <= tolerance*2 &&
+		relativeError(total.Carbs, targets.Carbs) <= tolerance*2
+}
+
+// Относительная ошибка, нулевая норма не учитывается
+func relativeError(value, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	return math.Abs(value-target) / target
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"time"
 )
 
 // Категории продуктов
@@ -35,6 +36,15 @@ const (
 	COMPOSITE      ProductType = "composite" // составные продукты
 )
 
+// Статус модерации продукта
+type SubmissionStatus string
+
+const (
+	PENDING  SubmissionStatus = "pending"  // ожидает проверки редактором
+	APPROVED SubmissionStatus = "approved" // опубликован в каталоге
+	REJECTED SubmissionStatus = "rejected" // отклонен редактором
+)
+
 type Unit string
 
 const (
@@ -52,6 +62,13 @@ const (
 	SLICE      Unit = "slice"
 	BUNCH      Unit = "bunch" // пучок (зелень)
 	CLOVE      Unit = "clove" // зубчик (чеснок)
+
+	// Американские единицы
+	OUNCE       Unit = "oz"
+	POUND       Unit = "lb"
+	FLUID_OUNCE Unit = "fl_oz"
+	PINT        Unit = "pt"
+	QUART       Unit = "qt"
 )
 
 type Product struct {
@@ -70,6 +87,20 @@ type Product struct {
 	IsVegetarian bool            `json:"is_vegetarian" gorm:"default:false"`
 	IsVegan      bool            `json:"is_vegan" gorm:"default:false"`
 	IsGlutenFree bool            `json:"is_gluten_free" gorm:"default:false"`
+	Density      *float64        `json:"density" gorm:"check:density > 0"` // г/мл, для перевода объема в вес
+	// Доля несъедобных частей (кожура, скорлупа, кости) в процентах
+	RefusePercent *float64 `json:"refuse_percent" gorm:"check:refuse_percent >= 0 AND refuse_percent < 100"`
+
+	// Модерация
+	Status      SubmissionStatus `json:"status" gorm:"not null;default:'approved';index"`
+	SubmittedBy *uint            `json:"submitted_by" gorm:"index"`
+	ReviewedBy  *uint            `json:"reviewed_by"`
+	ReviewedAt  *time.Time       `json:"reviewed_at"`
+	ReviewNotes string           `json:"review_notes" gorm:"type:text"`
+
+	// Перевод единиц для этого продукта
+	UnitWeights []ProductUnitWeight `json:"unit_weights,omitempty" gorm:"foreignKey:ProductID"`
+	Servings    []ProductServing    `json:"servings,omitempty" gorm:"foreignKey:ProductID"`
 }
 
 // Альтернативные названия для поиска
@@ -81,26 +112,185 @@ type ProductAlias struct {
 
 // Пищевая ценность для конкретного количества
 type NutritionInfo struct {
-	Calories float64
-	Fats     float64
-	Protein  float64
-	Carbs    float64
-	Weight   float64 // вес в граммах
-}
-
-// Коэффициенты для перевода единиц в граммы
-var unitToGramMultiplier = map[Unit]float64{
-	GRAM:       1.0,
-	KILOGRAM:   1000.0,
-	LITER:      1000.0, // приблизительно для воды
-	MILLILITER: 1.0,    // приблизительно для воды
-	PIECE:      0.0,    // зависит от продукта
-	TABLESPOON: 15.0,   // приблизительно
-	TEASPOON:   5.0,    // приблизительно
-	CUP:        240.0,  // приблизительно
-	SLICE:      0.0,    // зависит от продукта
-	BUNCH:      0.0,    // зависит от продукта
-	CLOVE:      3.0,    // средний зубчик чеснока
+	Calories float64 `json:"calories"`
+	Fats     float64 `json:"fats"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Weight   float64 `json:"weight"` // вес в граммах
+
+	// Объем переведен в вес по плотности воды, значение может быть неточным
+	WaterDensityUsed bool `json:"water_density_used,omitempty"`
+
+	// Насколько точен перевод в граммы и в каких пределах лежат значения
+	Confidence Confidence      `json:"confidence,omitempty"`
+	Range      *NutritionRange `json:"range,omitempty"`
+}
+
+func (n *NutritionInfo) Add(other NutritionInfo) {
+	if n.Range != nil || other.Range != nil {
+		nb, ob := n.bounds(), other.bounds()
+		n.Range = &NutritionRange{Min: nb.Min.add(ob.Min), Max: nb.Max.add(ob.Max)}
+	}
+	n.Calories += other.Calories
+	n.Fats += other.Fats
+	n.Protein += other.Protein
+	n.Carbs += other.Carbs
+	n.Weight += other.Weight
+	n.WaterDensityUsed = n.WaterDensityUsed || other.WaterDensityUsed
+	n.Confidence = lowerConfidence(n.Confidence, other.Confidence)
+}
+
+// Пищевая ценность, умноженная на коэффициент
+func (n NutritionInfo) Scale(factor float64) NutritionInfo {
+	scaled := NutritionInfo{
+		Calories: n.Calories * factor,
+		Fats:     n.Fats * factor,
+		Protein:  n.Protein * factor,
+		Carbs:    n.Carbs * factor,
+		Weight:   n.Weight * factor,
+
+		WaterDensityUsed: n.WaterDensityUsed,
+		Confidence:       n.Confidence,
+	}
+	if n.Range != nil {
+		scaled.Range = &NutritionRange{Min: n.Range.Min.scale(factor), Max: n.Range.Max.scale(factor)}
+		if factor < 0 {
+			scaled.Range.Min, scaled.Range.Max = scaled.Range.Max, scaled.Range.Min
+		}
+	}
+	return scaled
+}
+
+// Коэффициенты для перевода единиц массы в граммы
+var massUnitGrams = map[Unit]float64{
+	GRAM:     1.0,
+	KILOGRAM: 1000.0,
+	OUNCE:    28.3495,
+	POUND:    453.592,
+}
+
+// Объем единиц в миллилитрах
+var volumeUnitMilliliters = map[Unit]float64{
+	LITER:      1000.0,
+	MILLILITER: 1.0,
+	TABLESPOON: 15.0,
+	TEASPOON:   5.0,
+	CUP:        240.0,
+
+	FLUID_OUNCE: 29.5735,
+	PINT:        473.176, // американская пинта
+	QUART:       946.353, // американская кварта
+}
+
+// Единицы, вес которых зависит от продукта
+var countUnits = map[Unit]bool{
+	PIECE: true,
+	SLICE: true,
+	BUNCH: true,
+	CLOVE: true,
+}
+
+// Все единицы в порядке показа пользователю
+var standardUnits = []Unit{
+	GRAM, KILOGRAM, OUNCE, POUND,
+	MILLILITER, LITER, TEASPOON, TABLESPOON, CUP, FLUID_OUNCE, PINT, QUART,
+	PIECE, SLICE, BUNCH, CLOVE,
+	PACKAGE, BOTTLE, CAN,
+}
+
+// Упаковки, вес которых задается для продукта
+var packageUnits = map[Unit]bool{
+	PACKAGE: true,
+	BOTTLE:  true,
+	CAN:     true,
+}
+
+// Плотность по умолчанию для категорий, г/мл
+var categoryDensity = map[ProductCategory]float64{
+	OIL:      0.92, // растительное масло
+	DAIRY:    1.03, // молоко
+	SAUCE:    1.10, // кетчуп, майонез
+	BEVERAGE: 1.04, // соки
+	GRAIN:    0.75, // крупы, мука заметно легче
+	SPICE:    1.20, // соль, молотые специи легче
+	SWEET:    0.85, // сахарный песок
+	SNACK:    0.50, // орехи
+}
+
+// Плотность воды - если о продукте ничего не известно
+const waterDensity = 1.0
+
+// Способ перевода количества в граммы
+type ConversionMethod string
+
+const (
+	CONVERSION_EXACT            ConversionMethod = "exact"            // единицы массы
+	CONVERSION_UNIT_WEIGHT      ConversionMethod = "unit_weight"      // вес единицы задан для продукта
+	CONVERSION_SERVING          ConversionMethod = "serving"          // порция с упаковки продукта
+	CONVERSION_PRODUCT_DENSITY  ConversionMethod = "product_density"  // плотность задана для продукта
+	CONVERSION_CATEGORY_DENSITY ConversionMethod = "category_density" // плотность по категории
+	CONVERSION_WATER_DENSITY    ConversionMethod = "water_density"    // плотность воды
+	CONVERSION_CATEGORY_AVERAGE ConversionMethod = "category_average" // средний вес штуки по категории
+)
+
+// Насколько точен способ перевода: больше - точнее
+var conversionPrecision = map[ConversionMethod]int{
+	CONVERSION_EXACT:            5,
+	CONVERSION_UNIT_WEIGHT:      4,
+	CONVERSION_SERVING:          4,
+	CONVERSION_PRODUCT_DENSITY:  3,
+	CONVERSION_CATEGORY_DENSITY: 2,
+	CONVERSION_CATEGORY_AVERAGE: 1,
+	CONVERSION_WATER_DENSITY:    0,
+}
+
+// Результат перевода в граммы
+type Conversion struct {
+	Grams  float64          `json:"grams"`
+	Method ConversionMethod `json:"method"`
+
+	// В каком виде получен вес: средний вес штуки включает кожуру
+	// и кости, вес единицы и порции продукта - только съедобную часть.
+	// Для веса и объема пусто - вид зависит от того, как указано количество
+	Basis AmountBasis `json:"basis,omitempty"`
+}
+
+// Результат перевода между единицами
+type UnitConversion struct {
+	Amount float64          `json:"amount"`
+	From   Unit             `json:"from"`
+	To     Unit             `json:"to"`
+	Result float64          `json:"result"`
+	Method ConversionMethod `json:"method"`
+
+	Confidence Confidence `json:"confidence"`
+}
+
+func ValidateUnit(unit Unit) error {
+	if _, ok := massUnitGrams[unit]; ok {
+		return nil
+	}
+	if _, ok := volumeUnitMilliliters[unit]; ok {
+		return nil
+	}
+	if countUnits[unit] || packageUnits[unit] {
+		return nil
+	}
+	return errors.New("unsupported unit")
+}
+
+func IsMassUnit(unit Unit) bool {
+	_, ok := massUnitGrams[unit]
+	return ok
+}
+
+func IsVolumeUnit(unit Unit) bool {
+	_, ok := volumeUnitMilliliters[unit]
+	return ok
+}
+
+func IsPackageUnit(unit Unit) bool {
+	return packageUnits[unit]
 }
 
 func ValidateProductCategory(category ProductCategory) error {
@@ -121,6 +311,15 @@ func ValidateProductType(productType ProductType) error {
 	}
 }
 
+func ValidateSubmissionStatus(status SubmissionStatus) error {
+	switch status {
+	case PENDING, APPROVED, REJECTED:
+		return nil
+	default:
+		return errors.New("invalid submission status")
+	}
+}
+
 func (p *Product) HasNutritionInfo() bool {
 	return p.Calories != nil && p.Fats != nil && p.Protein != nil && p.Carbs != nil
 }
@@ -129,43 +328,182 @@ func (p *Product) IsReadyToEat() bool {
 	return p.Type == READY_PRODUCT
 }
 
+func (p *Product) IsApproved() bool {
+	return p.Status == APPROVED
+}
+
+// Виден ли продукт пользователю: опубликованные видны всем,
+// остальные - автору и редакторам
+func (p *Product) IsVisibleTo(user *User) bool {
+	if p.IsApproved() {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	return user.IsEditor() || (p.SubmittedBy != nil && *p.SubmittedBy == user.ID)
+}
+
+// Менять данные продукта могут редакторы, а автор - пока продукт не опубликован
+func (p *Product) CanBeEditedBy(user *User) bool {
+	if user == nil {
+		return false
+	}
+	if user.IsEditor() {
+		return true
+	}
+	return !p.IsApproved() && p.SubmittedBy != nil && *p.SubmittedBy == user.ID
+}
+
+// Решение редактора по заявке
+func (p *Product) Review(reviewer *User, status SubmissionStatus, notes string) error {
+	if err := ValidateSubmissionStatus(status); err != nil {
+		return err
+	}
+	if status == PENDING {
+		return errors.New("review must approve or reject the product")
+	}
+
+	now := time.Now()
+	p.Status = status
+	p.ReviewNotes = notes
+	p.ReviewedBy = &reviewer.ID
+	p.ReviewedAt = &now
+	return nil
+}
+
 // Рассчитать пищевую ценность для конкретного количества
 func (p *Product) CalculateNutrition(amount float64, unit Unit) (*NutritionInfo, error) {
+	return p.CalculateNutritionAs(amount, unit, BASIS_EDIBLE)
+}
+
+// Рассчитать пищевую ценность для количества, указанного съедобной
+// частью или как куплено
+func (p *Product) CalculateNutritionAs(amount float64, unit Unit, basis AmountBasis) (*NutritionInfo, error) {
 	if !p.HasNutritionInfo() {
 		return nil, errors.New("nutrition information not available for this product")
 	}
 
-	// Конвертируем в граммы
-	weightInGrams, err := p.convertToGrams(amount, unit)
+	// Конвертируем в граммы съедобной части
+	conversion, err := p.ConvertEdible(amount, unit, basis)
 	if err != nil {
 		return nil, err
 	}
+	weightInGrams := conversion.Grams
 
 	// Рассчитываем пищевую ценность пропорционально
 	factor := weightInGrams / 100.0 // пищевая ценность указана на 100г
 
-	return &NutritionInfo{
+	nutrition := &NutritionInfo{
 		Calories: *p.Calories * factor,
 		Fats:     *p.Fats * factor,
 		Protein:  *p.Protein * factor,
 		Carbs:    *p.Carbs * factor,
 		Weight:   weightInGrams,
-	}, nil
+	}
+	nutrition.setConversion(conversion.Method)
+	return nutrition, nil
 }
 
-// Конвертация в граммы
-func (p *Product) convertToGrams(amount float64, unit Unit) (float64, error) {
-	multiplier, exists := unitToGramMultiplier[unit]
-	if !exists {
-		return 0, errors.New("unsupported unit")
+// Конвертация в граммы с указанием способа. Вес единицы и порции,
+// заданные для продукта, важнее плотности и средних значений по категории
+func (p *Product) Convert(amount float64, unit Unit) (*Conversion, error) {
+	if grams, ok := massUnitGrams[unit]; ok {
+		return &Conversion{Grams: amount * grams, Method: CONVERSION_EXACT}, nil
+	}
+
+	if w, ok := p.unitWeight(unit); ok {
+		return &Conversion{Grams: amount * w.EdibleGrams(), Method: CONVERSION_UNIT_WEIGHT, Basis: BASIS_EDIBLE}, nil
+	}
+
+	if s, ok := p.serving(unit); ok {
+		return &Conversion{Grams: amount * s.Grams, Method: CONVERSION_SERVING, Basis: BASIS_EDIBLE}, nil
+	}
+
+	if err := ValidateUnit(unit); err != nil {
+		return nil, err
+	}
+
+	// Вес упаковки знает только сам продукт
+	if IsPackageUnit(unit) {
+		return nil, errors.New("package size is not set for this product")
+	}
+
+	if ml, ok := volumeUnitMilliliters[unit]; ok {
+		density, method := p.density()
+		return &Conversion{Grams: amount * ml * density, Method: method}, nil
 	}
 
 	// Для единиц, которые зависят от продукта
-	if multiplier == 0.0 {
-		return p.getProductSpecificWeight(amount, unit)
+	grams, err := p.getProductSpecificWeight(amount, unit)
+	if err != nil {
+		return nil, err
+	}
+	return &Conversion{Grams: grams, Method: CONVERSION_CATEGORY_AVERAGE, Basis: BASIS_AS_PURCHASED}, nil
+}
+
+func (p *Product) convertToGrams(amount float64, unit Unit) (float64, error) {
+	conversion, err := p.Convert(amount, unit)
+	if err != nil {
+		return 0, err
+	}
+	return conversion.Grams, nil
+}
+
+// Перевести количество из одной единицы в другую. Единицы массы и объема
+// переводятся между собой только для продукта, по его плотности
+func ConvertUnits(p *Product, amount float64, from, to Unit) (*UnitConversion, error) {
+	result := &UnitConversion{Amount: amount, From: from, To: to}
+	switch {
+	case IsMassUnit(from) && IsMassUnit(to):
+		result.Result = amount * massUnitGrams[from] / massUnitGrams[to]
+		result.Method, result.Confidence = CONVERSION_EXACT, CONFIDENCE_HIGH
+		return result, nil
+	case IsVolumeUnit(from) && IsVolumeUnit(to):
+		result.Result = amount * volumeUnitMilliliters[from] / volumeUnitMilliliters[to]
+		result.Method, result.Confidence = CONVERSION_EXACT, CONFIDENCE_HIGH
+		return result, nil
+	case p == nil:
+		if err := ValidateUnit(from); err != nil {
+			return nil, err
+		}
+		if err := ValidateUnit(to); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("product is required to convert between these units")
+	}
+
+	source, err := p.Convert(amount, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := p.Convert(1, to)
+	if err != nil {
+		return nil, err
+	}
+	if target.Grams <= 0 {
+		return nil, errors.New("cannot convert to a unit with zero weight")
 	}
 
-	return amount * multiplier, nil
+	result.Result = source.Grams / target.Grams
+	// Точность перевода определяет менее точная из двух сторон
+	result.Method = source.Method
+	if conversionPrecision[target.Method] < conversionPrecision[source.Method] {
+		result.Method = target.Method
+	}
+	result.Confidence = result.Method.Confidence()
+	return result, nil
+}
+
+// Плотность продукта в г/мл: своя, по категории или воды
+func (p *Product) density() (float64, ConversionMethod) {
+	if p.Density != nil && *p.Density > 0 {
+		return *p.Density, CONVERSION_PRODUCT_DENSITY
+	}
+	if density, ok := categoryDensity[p.Category]; ok {
+		return density, CONVERSION_CATEGORY_DENSITY
+	}
+	return waterDensity, CONVERSION_WATER_DENSITY
 }
 
 // Вес для продукто-специфичных единиц
@@ -177,6 +515,8 @@ func (p *Product) getProductSpecificWeight(amount float64, unit Unit) (float64,
 		return p.getSliceWeight(amount)
 	case BUNCH:
 		return p.getBunchWeight(amount)
+	case CLOVE:
+		return amount * 3.0, nil // средний зубчик чеснока
 	default:
 		return 0, errors.New("unsupported product-specific unit")
 	}
@@ -0,0 +1,235 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+type Sex string
+
+const (
+	SEX_MALE   Sex = "male"
+	SEX_FEMALE Sex = "female"
+)
+
+// Уровень физической активности
+type ActivityLevel string
+
+const (
+	ACTIVITY_SEDENTARY   ActivityLevel = "sedentary"   // сидячий образ жизни
+	ACTIVITY_LIGHT       ActivityLevel = "light"       // 1-3 тренировки в неделю
+	ACTIVITY_MODERATE    ActivityLevel = "moderate"    // 3-5 тренировок в неделю
+	ACTIVITY_ACTIVE      ActivityLevel = "active"      // 6-7 тренировок в неделю
+	ACTIVITY_VERY_ACTIVE ActivityLevel = "very_active" // тяжелый физический труд, спорт
+)
+
+type Goal string
+
+const (
+	GOAL_LOSE     Goal = "lose"     // похудение
+	GOAL_MAINTAIN Goal = "maintain" // поддержание веса
+	GOAL_GAIN     Goal = "gain"     // набор массы
+)
+
+// Формула основного обмена
+type BMRFormula string
+
+const (
+	MIFFLIN_ST_JEOR BMRFormula = "mifflin_st_jeor"
+	HARRIS_BENEDICT BMRFormula = "harris_benedict"
+)
+
+// Коэффициенты активности для расчета TDEE
+var activityMultiplier = map[ActivityLevel]float64{
+	ACTIVITY_SEDENTARY:   1.2,
+	ACTIVITY_LIGHT:       1.375,
+	ACTIVITY_MODERATE:    1.55,
+	ACTIVITY_ACTIVE:      1.725,
+	ACTIVITY_VERY_ACTIVE: 1.9,
+}
+
+// Поправка калорийности под цель
+var goalCalorieMultiplier = map[Goal]float64{
+	GOAL_LOSE:     0.8, // дефицит 20%
+	GOAL_MAINTAIN: 1.0,
+	GOAL_GAIN:     1.1, // профицит 10%
+}
+
+// Белок в граммах на кг веса
+var goalProteinPerKg = map[Goal]float64{
+	GOAL_LOSE:     2.0,
+	GOAL_MAINTAIN: 1.6,
+	GOAL_GAIN:     1.8,
+}
+
+// Доля жиров в калорийности рациона
+const fatCalorieShare = 0.25
+
+// Калорийность макронутриентов, ккал на грамм
+const (
+	kcalPerGramProtein = 4.0
+	kcalPerGramCarbs   = 4.0
+	kcalPerGramFat     = 9.0
+)
+
+type Profile struct {
+	ID       uint          `json:"id" gorm:"primaryKey"`
+	UserID   uint          `json:"user_id" gorm:"not null;uniqueIndex"`
+	Sex      Sex           `json:"sex" gorm:"not null"`
+	Age      int           `json:"age" gorm:"not null;check:age > 0"`
+	HeightCm float64       `json:"height_cm" gorm:"not null;check:height_cm > 0"`
+	WeightKg float64       `json:"weight_kg" gorm:"not null;check:weight_kg > 0"`
+	Activity ActivityLevel `json:"activity" gorm:"not null;default:'sedentary'"`
+	Goal     Goal          `json:"goal" gorm:"not null;default:'maintain'"`
+	Formula  BMRFormula    `json:"formula" gorm:"not null;default:'mifflin_st_jeor'"`
+
+	GoalWeightKg *float64 `json:"goal_weight_kg" gorm:"check:goal_weight_kg > 0"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Дневные нормы
+type MacroTargets struct {
+	Formula  BMRFormula `json:"formula"`
+	BMR      float64    `json:"bmr"`
+	TDEE     float64    `json:"tdee"`
+	Calories float64    `json:"calories"`
+	Protein  float64    `json:"protein"`
+	Fats     float64    `json:"fats"`
+	Carbs    float64    `json:"carbs"`
+}
+
+func ValidateSex(sex Sex) error {
+	switch sex {
+	case SEX_MALE, SEX_FEMALE:
+		return nil
+	default:
+		return errors.New("invalid sex")
+	}
+}
+
+func ValidateActivityLevel(level ActivityLevel) error {
+	if _, ok := activityMultiplier[level]; !ok {
+		return errors.New("invalid activity level")
+	}
+	return nil
+}
+
+func ValidateGoal(goal Goal) error {
+	switch goal {
+	case GOAL_LOSE, GOAL_MAINTAIN, GOAL_GAIN:
+		return nil
+	default:
+		return errors.New("invalid goal")
+	}
+}
+
+func ValidateBMRFormula(formula BMRFormula) error {
+	switch formula {
+	case MIFFLIN_ST_JEOR, HARRIS_BENEDICT:
+		return nil
+	default:
+		return errors.New("invalid bmr formula")
+	}
+}
+
+func (p *Profile) Validate() error {
+	if err := ValidateSex(p.Sex); err != nil {
+		return err
+	}
+	if err := ValidateActivityLevel(p.Activity); err != nil {
+		return err
+	}
+	if err := ValidateGoal(p.Goal); err != nil {
+		return err
+	}
+	if err := ValidateBMRFormula(p.Formula); err != nil {
+		return err
+	}
+	if p.Age <= 0 || p.Age > 120 {
+		return errors.New("age must be between 1 and 120")
+	}
+	if p.HeightCm <= 0 || p.WeightKg <= 0 {
+		return errors.New("height and weight must be positive")
+	}
+	if p.GoalWeightKg != nil && *p.GoalWeightKg <= 0 {
+		return errors.New("goal weight must be positive")
+	}
+	return nil
+}
+
+// Основной обмен, ккал в сутки
+func (p *Profile) BMR(formula BMRFormula) (float64, error) {
+	w, h, a := p.WeightKg, p.HeightCm, float64(p.Age)
+
+	switch formula {
+	case MIFFLIN_ST_JEOR:
+		bmr := 10*w + 6.25*h - 5*a
+		if p.Sex == SEX_MALE {
+			return bmr + 5, nil
+		}
+		return bmr - 161, nil
+	case HARRIS_BENEDICT:
+		// Пересмотренная формула (Roza, Shizgal, 1984)
+		if p.Sex == SEX_MALE {
+			return 88.362 + 13.397*w + 4.799*h - 5.677*a, nil
+		}
+		return 447.593 + 9.247*w + 3.098*h - 4.330*a, nil
+	default:
+		return 0, errors.New("invalid bmr formula")
+	}
+}
+
+// Суточный расход энергии с учетом активности
+func (p *Profile) TDEE(formula BMRFormula) (float64, error) {
+	bmr, err := p.BMR(formula)
+	if err != nil {
+		return 0, err
+	}
+	multiplier, ok := activityMultiplier[p.Activity]
+	if !ok {
+		return 0, errors.New("invalid activity level")
+	}
+	return bmr * multiplier, nil
+}
+
+// Дневные нормы калорий и БЖУ по выбранной в профиле формуле
+func (p *Profile) Targets() (*MacroTargets, error) {
+	bmr, err := p.BMR(p.Formula)
+	if err != nil {
+		return nil, err
+	}
+	tdee, err := p.TDEE(p.Formula)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateGoal(p.Goal); err != nil {
+		return nil, err
+	}
+
+	calories := tdee * goalCalorieMultiplier[p.Goal]
+	protein := p.WeightKg * goalProteinPerKg[p.Goal]
+	fats := calories * fatCalorieShare / kcalPerGramFat
+	carbs := (calories - protein*kcalPerGramProtein - fats*kcalPerGramFat) / kcalPerGramCarbs
+
+	return &MacroTargets{
+		Formula:  p.Formula,
+		BMR:      math.Round(bmr),
+		TDEE:     math.Round(tdee),
+		Calories: math.Round(calories),
+		Protein:  math.Round(protein),
+		Fats:     math.Round(fats),
+		Carbs:    math.Round(math.Max(carbs, 0)),
+	}, nil
+}
+
+// Нормы в виде NutritionInfo, чтобы сравнивать с дневником
+func (t *MacroTargets) Nutrition() NutritionInfo {
+	return NutritionInfo{
+		Calories: t.Calories,
+		Fats:     t.Fats,
+		Protein:  t.Protein,
+		Carbs:    t.Carbs,
+	}
+}
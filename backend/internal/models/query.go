@@ -1 +1,36 @@
 package models
+
+import "gorm.io/gorm"
+
+// Продукты в публичном поиске: опубликованные и собственные заявки пользователя
+func VisibleProducts(user *User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user == nil {
+			return db.Where("status = ?", APPROVED)
+		}
+		return db.Where("status = ? OR submitted_by = ?", APPROVED, user.ID)
+	}
+}
+
+// Блюда, доступные пользователю: общие, публичные и собственные
+func VisibleDishes(user *User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user == nil {
+			return db.Where("user_id IS NULL OR is_public")
+		}
+		return db.Where("user_id IS NULL OR is_public OR user_id = ?", user.ID)
+	}
+}
+
+// Загрузить продукт по пути (например "Ingredients.Product") вместе с данными
+// для перевода единиц. Пустой путь - для самого запроса по продуктам
+func PreloadProduct(path string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		prefix := ""
+		if path != "" {
+			db = db.Preload(path)
+			prefix = path + "."
+		}
+		return db.Preload(prefix + "UnitWeights").Preload(prefix + "Servings")
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import "errors"
+
+// В каком виде указано количество продукта
+type AmountBasis string
+
+const (
+	BASIS_EDIBLE       AmountBasis = "edible"       // съедобная часть: очищенный банан, мясо без костей
+	BASIS_AS_PURCHASED AmountBasis = "as_purchased" // как куплено: с кожурой, скорлупой, костями
+)
+
+func ValidateAmountBasis(basis AmountBasis) error {
+	switch basis {
+	case "", BASIS_EDIBLE, BASIS_AS_PURCHASED:
+		return nil
+	default:
+		return errors.New("invalid amount basis")
+	}
+}
+
+func ValidateRefusePercent(percent *float64) error {
+	if percent != nil && (*percent < 0 || *percent >= 100) {
+		return errors.New("refuse percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Доля съедобной части продукта
+func (p *Product) edibleShare() float64 {
+	if p.RefusePercent == nil || *p.RefusePercent <= 0 || *p.RefusePercent >= 100 {
+		return 1.0
+	}
+	return 1 - *p.RefusePercent/100
+}
+
+// Вес съедобной части. Basis в результате - вид, в котором был
+// получен исходный вес
+func (p *Product) ConvertEdible(amount float64, unit Unit, basis AmountBasis) (*Conversion, error) {
+	conversion, err := p.Convert(amount, unit)
+	if err != nil {
+		return nil, err
+	}
+	if conversion.Basis == "" {
+		conversion.Basis = basis
+		if conversion.Basis == "" {
+			conversion.Basis = BASIS_EDIBLE
+		}
+	}
+	if conversion.Basis == BASIS_AS_PURCHASED {
+		conversion.Grams *= p.edibleShare()
+	}
+	return conversion, nil
+}
+
+// Сколько нужно купить, чтобы получить количество в той же единице.
+// Штуки, порции и упаковки и так покупаются целиком, поэтому
+// пересчитываются только вес и объем
+func (p *Product) PurchasedAmount(amount float64, unit Unit, basis AmountBasis) float64 {
+	if basis != BASIS_AS_PURCHASED && (IsMassUnit(unit) || IsVolumeUnit(unit)) {
+		return amount / p.edibleShare()
+	}
+	return amount
+}
+
+// Пищевая ценность ингредиента. Product должен быть загружен
+func (i *Ingredient) Nutrition() (*NutritionInfo, error) {
+	return i.Product.CalculateNutritionAs(i.Amount, i.Unit, i.AmountBasis)
+}
+
+// Количество ингредиента для покупки и списания из запасов.
+// Product должен быть загружен
+func (i *Ingredient) PurchasedAmount() float64 {
+	return i.Product.PurchasedAmount(i.Amount, i.Unit, i.AmountBasis)
+}
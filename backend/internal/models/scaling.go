@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+// Шаг округления для штучных единиц: половинку яйца отмерить можно,
+// а треть зубчика чеснока - уже нет
+var countUnitStep = map[Unit]float64{
+	PIECE:   0.5,
+	CLOVE:   1,
+	SLICE:   1,
+	BUNCH:   0.5,
+	PACKAGE: 0.5,
+	BOTTLE:  0.5,
+	CAN:     0.5,
+}
+
+// Шаг округления для мерных ложек и стаканов
+var measureUnitStep = map[Unit]float64{
+	TABLESPOON: 0.25,
+	TEASPOON:   0.25,
+	CUP:        0.25,
+	PINT:       0.25,
+	QUART:      0.25,
+}
+
+// Блюдо, пересчитанное на другое число порций или вес
+type ScaledDish struct {
+	Dish      Dish           `json:"dish"`
+	Factor    float64        `json:"factor"`
+	Servings  float64        `json:"servings"`
+	Nutrition *DishNutrition `json:"nutrition,omitempty"`
+}
+
+// Округлить количество ингредиента с учетом единицы измерения
+func RoundAmount(amount float64, unit Unit) float64 {
+	if step, ok := countUnitStep[unit]; ok {
+		// Ненулевое количество не округляем до нуля
+		return math.Max(math.Round(amount/step)*step, step)
+	}
+	if step, ok := measureUnitStep[unit]; ok {
+		return math.Max(math.Round(amount/step)*step, step)
+	}
+
+	switch unit {
+	case KILOGRAM, LITER, POUND:
+		return math.Round(amount*100) / 100
+	default:
+		if amount >= 10 {
+			return math.Round(amount)
+		}
+		return math.Round(amount*10) / 10
+	}
+}
+
+// Пересчитать рецепт с коэффициентом. Исходное блюдо не меняется
+func (d *Dish) Scale(factor float64) (*ScaledDish, error) {
+	if factor <= 0 {
+		return nil, errors.New("scale factor must be positive")
+	}
+
+	scaled := *d
+	if d.CookedWeight != nil {
+		cooked := *d.CookedWeight * factor
+		scaled.CookedWeight = &cooked
+	}
+	scaled.Ingredients = make([]Ingredient, len(d.Ingredients))
+	for i, ingredient := range d.Ingredients {
+		ingredient.Amount = RoundAmount(ingredient.Amount*factor, ingredient.Unit)
+		scaled.Ingredients[i] = ingredient
+	}
+
+	servings := float64(max(d.Servings, 1)) * factor
+	result := &ScaledDish{Dish: scaled, Factor: factor, Servings: servings}
+
+	// Пищевая ценность считается по округленным количествам
+	if nutrition, err := scaled.CalculateTotalNutrition(); err == nil {
+		nutrition.PerServing = nutrition.Total().Scale(1 / servings)
+		result.Nutrition = nutrition
+	}
+	return result, nil
+}
+
+// Пересчитать рецепт на нужное число порций
+func (d *Dish) ScaleToServings(servings float64) (*ScaledDish, error) {
+	if servings <= 0 {
+		return nil, errors.New("servings must be positive")
+	}
+	return d.Scale(servings / float64(max(d.Servings, 1)))
+}
+
+// Пересчитать рецепт на общий вес готового блюда в граммах
+func (d *Dish) ScaleToWeight(grams float64) (*ScaledDish, error) {
+	if grams <= 0 {
+		return nil, errors.New("total grams must be positive")
+	}
+	nutrition, err := d.CalculateTotalNutrition()
+	if err != nil {
+		return nil, err
+	}
+	if nutrition.CookedWeight == 0 {
+		return nil, errors.New("cannot scale by weight: total weight is zero")
+	}
+	return d.Scale(grams / nutrition.CookedWeight)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Порция, указанная на упаковке: "1 батончик = 45 г". Название
+// порции можно использовать как единицу измерения продукта
+type ProductServing struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	ProductID uint    `json:"product_id" gorm:"not null;uniqueIndex:idx_product_serving"`
+	Name      string  `json:"name" gorm:"not null;uniqueIndex:idx_product_serving"`
+	Grams     float64 `json:"grams" gorm:"not null;check:grams > 0"`
+}
+
+func normalizeServingName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (s *ProductServing) Validate() error {
+	s.Name = normalizeServingName(s.Name)
+	if s.Name == "" {
+		return errors.New("serving name is required")
+	}
+	// Иначе порция перекрыла бы обычную единицу при переводе в граммы
+	if ValidateUnit(Unit(s.Name)) == nil {
+		return errors.New("serving name cannot be a unit of measure")
+	}
+	if s.Grams <= 0 {
+		return errors.New("grams must be positive")
+	}
+	return nil
+}
+
+// Порция продукта с таким названием. Servings должны быть загружены
+func (p *Product) serving(unit Unit) (*ProductServing, bool) {
+	name := normalizeServingName(string(unit))
+	for i := range p.Servings {
+		if p.Servings[i].Name == name {
+			return &p.Servings[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+)
+
+// Префиксы токенов сессий
+const (
+	SessionTokenPrefix = "fs_"
+	RefreshTokenPrefix = "fr_"
+)
+
+const (
+	AccessTokenTTL = 15 * time.Minute    // время жизни токена доступа
+	SessionTTL     = 30 * 24 * time.Hour // сессия продлевается при каждом обновлении
+)
+
+type Session struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserID          uint      `json:"user_id" gorm:"not null;index"`
+	TokenHash       string    `json:"-" gorm:"not null;uniqueIndex"`
+	AccessExpiresAt time.Time `json:"-"`
+
+	// Refresh-токен меняется при каждом обновлении. Предыдущий хранится,
+	// чтобы заметить повторное использование украденного токена
+	RefreshTokenHash    string `json:"-" gorm:"uniqueIndex"`
+	PreviousRefreshHash string `json:"-" gorm:"index"`
+
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Открытые токены сессии, возвращаются клиенту только при выдаче
+type SessionTokens struct {
+	AccessToken     string    `json:"access_token"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	RefreshToken    string    `json:"refresh_token"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// Создать сессию и выдать первую пару токенов
+func NewSession(userID uint, userAgent, ip string) (*Session, *SessionTokens, error) {
+	s := &Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastUsedAt: time.Now(),
+	}
+	tokens, err := s.Rotate()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, tokens, nil
+}
+
+// Выдать новую пару токенов, старые перестают действовать
+func (s *Session) Rotate() (*SessionTokens, error) {
+	access, err := newToken(SessionTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := newToken(RefreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.TokenHash = HashToken(access)
+	s.AccessExpiresAt = now.Add(AccessTokenTTL)
+	s.PreviousRefreshHash = s.RefreshTokenHash
+	s.RefreshTokenHash = HashToken(refresh)
+	s.ExpiresAt = now.Add(SessionTTL)
+
+	return &SessionTokens{
+		AccessToken:     access,
+		AccessExpiresAt: s.AccessExpiresAt,
+		RefreshToken:    refresh,
+		ExpiresAt:       s.ExpiresAt,
+	}, nil
+}
+
+func (s *Session) Revoke() {
+	if s.RevokedAt == nil {
+		now := time.Now()
+		s.RevokedAt = &now
+	}
+}
+
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// Сессия не отозвана и не истекла
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && !s.IsExpired()
+}
+
+// Можно ли пользоваться текущим токеном доступа
+func (s *Session) IsAccessValid() bool {
+	return s.IsActive() && time.Now().Before(s.AccessExpiresAt)
+}
@@ -15,8 +15,17 @@ func InitDB() {
 	if err != nil {
 		log.Fatalln("failed to connect database.")
 	}
-	if err := db.AutoMigrate(&Product{}, &Dish{}); err != nil {
+	if err := db.AutoMigrate(
+		&User{}, &Session{}, &APIKey{},
+		&Product{}, &ProductUnitWeight{}, &ProductServing{}, &Dish{}, &Ingredient{},
+		&DiaryEntry{}, &Profile{}, &WeightEntry{}, &Activity{}, &ExerciseEntry{},
+		&MealPlan{}, &MealPlanEntry{},
+		&ShoppingList{}, &ShoppingListMember{}, &ShoppingListItem{}, &PantryItem{},
+	); err != nil {
 		log.Fatalln("something went wrong with migration:", err)
 	}
+	if err := seedActivities(db); err != nil {
+		log.Fatalln("something went wrong with seeding activities:", err)
+	}
 	DB = db
 }
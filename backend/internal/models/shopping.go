@@ -0,0 +1,133 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// Блюдо в списке покупок с коэффициентом от рецепта
+type ShoppingSource struct {
+	Dish   *Dish
+	Factor float64 // 1 - количество по рецепту, 2 - вдвое больше
+}
+
+// Количество продукта, которое уже есть или нужно купить
+type ProductAmount struct {
+	ProductID uint    `json:"product_id"`
+	Amount    float64 `json:"amount"`
+	Unit      Unit    `json:"unit"`
+}
+
+type ShoppingItem struct {
+	ProductID   uint            `json:"product_id"`
+	ProductName string          `json:"product_name"`
+	Category    ProductCategory `json:"category"`
+	Amount      float64         `json:"amount"`
+	Unit        Unit            `json:"unit"`
+
+	// Сколько упаковок купить, если размер упаковки задан для продукта
+	Packages    float64 `json:"packages,omitempty"`
+	PackageUnit Unit    `json:"package_unit,omitempty"`
+}
+
+type ShoppingCategory struct {
+	Category ProductCategory `json:"category"`
+	Items    []ShoppingItem  `json:"items"`
+}
+
+// Накопленное количество продукта: граммы для переводимых единиц
+// и отдельные суммы для единиц, которые в граммы не переводятся
+type productTotal struct {
+	product *Product
+	grams   float64
+	byUnit  map[Unit]float64
+}
+
+func (t *productTotal) add(amount float64, unit Unit) {
+	if grams, err := t.product.convertToGrams(amount, unit); err == nil {
+		t.grams += grams
+		return
+	}
+	t.byUnit[unit] += amount
+}
+
+// Вычесть запас. Что нельзя перевести в граммы, вычитается только из той же единицы
+func (t *productTotal) subtract(amount float64, unit Unit) {
+	if _, ok := t.byUnit[unit]; ok {
+		t.byUnit[unit] -= amount
+		return
+	}
+	if grams, err := t.product.convertToGrams(amount, unit); err == nil {
+		t.grams -= grams
+	}
+}
+
+// Собрать список покупок: ингредиенты суммируются по продуктам с учетом
+// отходов, из результата вычитается то, что уже есть в запасах.
+// Dish.Ingredients.Product должны быть загружены
+func BuildShoppingList(sources []ShoppingSource, pantry []ProductAmount) []ShoppingCategory {
+	totals := map[uint]*productTotal{}
+	for _, source := range sources {
+		for i := range source.Dish.Ingredients {
+			ingredient := &source.Dish.Ingredients[i]
+			total, ok := totals[ingredient.ProductID]
+			if !ok {
+				total = &productTotal{product: &ingredient.Product, byUnit: map[Unit]float64{}}
+				totals[ingredient.ProductID] = total
+			}
+			total.add(ingredient.PurchasedAmount()*source.Factor, ingredient.Unit)
+		}
+	}
+
+	for _, item := range pantry {
+		if total, ok := totals[item.ProductID]; ok {
+			total.subtract(item.Amount, item.Unit)
+		}
+	}
+
+	byCategory := map[ProductCategory][]ShoppingItem{}
+	for _, total := range totals {
+		p := total.product
+		newItem := func(amount float64, unit Unit) ShoppingItem {
+			return ShoppingItem{
+				ProductID:   p.ID,
+				ProductName: p.Name,
+				Category:    p.Category,
+				Amount:      math.Round(amount*100) / 100,
+				Unit:        unit,
+			}
+		}
+
+		if total.grams > 0 {
+			item := newItem(total.grams, GRAM)
+			if w, ok := p.packageSize(); ok {
+				item.Packages = packagesFor(total.grams, w.EdibleGrams())
+				item.PackageUnit = w.Unit
+			}
+			byCategory[p.Category] = append(byCategory[p.Category], item)
+		}
+		for unit, amount := range total.byUnit {
+			if amount > 0 {
+				byCategory[p.Category] = append(byCategory[p.Category], newItem(amount, unit))
+			}
+		}
+	}
+
+	result := make([]ShoppingCategory, 0, len(byCategory))
+	for category, items := range byCategory {
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].ProductName != items[j].ProductName {
+				return items[i].ProductName < items[j].ProductName
+			}
+			return items[i].Unit < items[j].Unit
+		})
+		result = append(result, ShoppingCategory{Category: category, Items: items})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result
+}
+
+// Число целых упаковок, которых хватит на нужный вес
+func packagesFor(grams, packageGrams float64) float64 {
+	return math.Ceil(math.Round(grams/packageGrams*1000) / 1000)
+}
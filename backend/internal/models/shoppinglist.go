@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Сохраненный список покупок, которым можно поделиться
+type ShoppingList struct {
+	ID        uint                 `json:"id" gorm:"primaryKey"`
+	OwnerID   uint                 `json:"owner_id" gorm:"not null;index"`
+	Name      string               `json:"name" gorm:"not null"`
+	Items     []ShoppingListItem   `json:"items,omitempty" gorm:"foreignKey:ShoppingListID"`
+	Members   []ShoppingListMember `json:"members,omitempty" gorm:"foreignKey:ShoppingListID"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// Пользователь, с которым поделились списком
+type ShoppingListMember struct {
+	ShoppingListID uint      `json:"shopping_list_id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"primaryKey;index"`
+	User           *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Позиция списка. Version растет при каждом изменении и защищает
+// от одновременной правки одной позиции несколькими людьми
+type ShoppingListItem struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	ShoppingListID uint            `json:"shopping_list_id" gorm:"not null;index"`
+	ProductID      *uint           `json:"product_id"`
+	Name           string          `json:"name" gorm:"not null"`
+	Category       ProductCategory `json:"category"`
+	Amount         float64         `json:"amount"`
+	Unit           Unit            `json:"unit"`
+	Packages       float64         `json:"packages,omitempty"`
+	PackageUnit    Unit            `json:"package_unit,omitempty"`
+	Checked        bool            `json:"checked" gorm:"not null;default:false"`
+	CheckedBy      *uint           `json:"checked_by"`
+	Version        int             `json:"version" gorm:"not null;default:1"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func (l *ShoppingList) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("shopping list name is required")
+	}
+	return nil
+}
+
+func (i *ShoppingListItem) Validate() error {
+	if strings.TrimSpace(i.Name) == "" {
+		return errors.New("item name is required")
+	}
+	if i.Amount < 0 {
+		return errors.New("item amount must not be negative")
+	}
+	return nil
+}
+
+// Позиции для сохранения из сгенерированного списка
+func ShoppingListItemsFrom(categories []ShoppingCategory) []ShoppingListItem {
+	var items []ShoppingListItem
+	for _, category := range categories {
+		for _, item := range category.Items {
+			productID := item.ProductID
+			items = append(items, ShoppingListItem{
+				ProductID: &productID,
+				Name:      item.ProductName,
+				Category:  item.Category,
+				Amount:    item.Amount,
+				Unit:      item.Unit,
+				Version:   1,
+
+				Packages:    item.Packages,
+				PackageUnit: item.PackageUnit,
+			})
+		}
+	}
+	return items
+}
+
+// Есть ли у пользователя доступ к списку. Members должны быть загружены
+func (l *ShoppingList) HasAccess(userID uint) bool {
+	if l.OwnerID == userID {
+		return true
+	}
+	for _, member := range l.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
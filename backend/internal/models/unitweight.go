@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+)
+
+// Вес одной единицы конкретного продукта: виноградина и арбуз
+// в штуках весят очень по-разному. Вес указывается для съедобной
+// части. Для упаковок (банка, бутылка, пачка) это вес содержимого,
+// а для консервов можно указать и вес без заливки
+type ProductUnitWeight struct {
+	ID           uint     `json:"id" gorm:"primaryKey"`
+	ProductID    uint     `json:"product_id" gorm:"not null;uniqueIndex:idx_product_unit"`
+	Unit         Unit     `json:"unit" gorm:"not null;uniqueIndex:idx_product_unit"`
+	Grams        float64  `json:"grams" gorm:"not null;check:grams > 0"`
+	DrainedGrams *float64 `json:"drained_grams,omitempty" gorm:"check:drained_grams > 0"`
+}
+
+func (w *ProductUnitWeight) Validate() error {
+	if err := ValidateUnit(w.Unit); err != nil {
+		return err
+	}
+	if IsMassUnit(w.Unit) {
+		return errors.New("weight units do not need a conversion")
+	}
+	if w.Grams <= 0 {
+		return errors.New("grams must be positive")
+	}
+	if w.DrainedGrams != nil {
+		if !IsPackageUnit(w.Unit) {
+			return errors.New("drained weight is only applicable to packages")
+		}
+		if *w.DrainedGrams <= 0 {
+			return errors.New("drained grams must be positive")
+		}
+		if *w.DrainedGrams > w.Grams {
+			return errors.New("drained grams cannot exceed grams")
+		}
+	}
+	return nil
+}
+
+// Сколько граммов единицы идет в еду: для консервов - без заливки
+func (w *ProductUnitWeight) EdibleGrams() float64 {
+	if w.DrainedGrams != nil {
+		return *w.DrainedGrams
+	}
+	return w.Grams
+}
+
+// Вес единицы, заданный для продукта. UnitWeights должны быть загружены
+func (p *Product) unitWeight(unit Unit) (*ProductUnitWeight, bool) {
+	for i := range p.UnitWeights {
+		if p.UnitWeights[i].Unit == unit {
+			return &p.UnitWeights[i], true
+		}
+	}
+	return nil, false
+}
+
+// Размер упаковки продукта, если он задан
+func (p *Product) packageSize() (*ProductUnitWeight, bool) {
+	for _, unit := range []Unit{PACKAGE, BOTTLE, CAN} {
+		if w, ok := p.unitWeight(unit); ok {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// Вес покупки в граммах: упаковка учитывается целиком, вместе с заливкой
+func (p *Product) purchaseGrams(amount float64, unit Unit) (float64, error) {
+	if w, ok := p.unitWeight(unit); ok && IsPackageUnit(unit) {
+		return amount * w.Grams, nil
+	}
+	return p.convertToGrams(amount, unit)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Роли пользователей
+type Role string
+
+const (
+	ROLE_USER   Role = "user"   // обычный пользователь
+	ROLE_EDITOR Role = "editor" // редактор каталога, модерирует продукты
+	ROLE_ADMIN  Role = "admin"  // администратор
+)
+
+type User struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"index"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role" gorm:"not null;default:'user'"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// В какой системе показывать количества
+	UnitSystem UnitSystem `json:"unit_system" gorm:"not null;default:'metric'"`
+
+	// Привязка к аккаунту у OIDC-провайдера
+	OIDCIssuer  *string `json:"-" gorm:"uniqueIndex:idx_users_oidc"`
+	OIDCSubject *string `json:"-" gorm:"uniqueIndex:idx_users_oidc"`
+}
+
+func ValidateRole(role Role) error {
+	switch role {
+	case ROLE_USER, ROLE_EDITOR, ROLE_ADMIN:
+		return nil
+	default:
+		return errors.New("invalid role")
+	}
+}
+
+// Может ли пользователь модерировать каталог
+func (u *User) IsEditor() bool {
+	return u.Role == ROLE_EDITOR || u.Role == ROLE_ADMIN
+}
+
+// Найти пользователя по subject провайдера или завести нового
+func FindOrCreateOIDCUser(issuer, subject, email, name string) (*User, error) {
+	var u User
+	err := DB.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(&u).Error
+	if err == nil {
+		if email != "" && email != u.Email || name != "" && name != u.Name {
+			if email != "" {
+				u.Email = email
+			}
+			if name != "" {
+				u.Name = name
+			}
+			err = DB.Model(&u).Updates(map[string]any{"email": u.Email, "name": u.Name}).Error
+		}
+		return &u, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	u = User{
+		Email:       email,
+		Name:        name,
+		Role:        ROLE_USER,
+		UnitSystem:  METRIC,
+		OIDCIssuer:  &issuer,
+		OIDCSubject: &subject,
+	}
+	if err := DB.Create(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,62 @@
+package models
+
+// Ошибка в конкретном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Единица, в которой можно указать количество продукта
+type UnitOption struct {
+	Unit Unit `json:"unit"`
+	Conversion
+	Confidence Confidence `json:"confidence"`
+}
+
+// Единицы, которые переводятся в граммы для этого продукта: стандартные
+// и порции продукта. UnitWeights и Servings должны быть загружены
+func (p *Product) AvailableUnits() []UnitOption {
+	units := append([]Unit{}, standardUnits...)
+	for _, s := range p.Servings {
+		units = append(units, Unit(s.Name))
+	}
+
+	var options []UnitOption
+	seen := map[Unit]bool{}
+	for _, unit := range units {
+		if seen[unit] {
+			continue
+		}
+		seen[unit] = true
+
+		conversion, err := p.Convert(1, unit)
+		if err != nil {
+			continue
+		}
+		options = append(options, UnitOption{
+			Unit:       unit,
+			Conversion: *conversion,
+			Confidence: conversion.Method.Confidence(),
+		})
+	}
+	return options
+}
+
+// Проверить ингредиент перед сохранением: количество указано, а единица
+// переводится в граммы для продукта. Product должен быть загружен
+func (i *Ingredient) Validate() []FieldError {
+	var errs []FieldError
+	if i.Amount <= 0 {
+		errs = append(errs, FieldError{Field: "Amount", Message: "amount must be positive"})
+	}
+	if _, err := i.Product.Convert(1, i.Unit); err != nil {
+		errs = append(errs, FieldError{Field: "Unit", Message: err.Error()})
+	}
+	if err := ValidateAmountBasis(i.AmountBasis); err != nil {
+		errs = append(errs, FieldError{Field: "AmountBasis", Message: err.Error()})
+	}
+	if i.YieldFactor != nil && *i.YieldFactor <= 0 {
+		errs = append(errs, FieldError{Field: "YieldFactor", Message: "yield factor must be positive"})
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Коэффициент сглаживания тренда за один день
+const weightTrendAlpha = 0.1
+
+// Окно в днях для расчета скорости изменения веса
+const weightRateWindow = 28
+
+type WeightEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_weight_user_date"`
+	Date      Date      `json:"date" gorm:"not null;uniqueIndex:idx_weight_user_date"`
+	WeightKg  float64   `json:"weight_kg" gorm:"not null;check:weight_kg > 0"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Точка ряда: измерение и сглаженный тренд
+type WeightTrendPoint struct {
+	Date     Date    `json:"date"`
+	WeightKg float64 `json:"weight_kg"`
+	TrendKg  float64 `json:"trend_kg"`
+}
+
+type WeightTrend struct {
+	Points        []WeightTrendPoint `json:"points"`
+	CurrentTrend  *float64           `json:"current_trend_kg"`
+	WeeklyRate    *float64           `json:"weekly_rate_kg"` // изменение тренда за неделю, кг
+	GoalWeightKg  *float64           `json:"goal_weight_kg"`
+	ProjectedDate *Date              `json:"projected_goal_date"` // nil, если вес не движется к цели
+}
+
+func (e *WeightEntry) Validate() error {
+	if e.WeightKg <= 0 || e.WeightKg > 500 {
+		return errors.New("weight must be between 0 and 500 kg")
+	}
+	return nil
+}
+
+// Экспоненциально сглаженный тренд. Записи должны быть отсортированы по дате.
+// Пропущенные дни учитываются: чем больше разрыв, тем сильнее вес нового измерения
+func CalculateWeightTrend(entries []WeightEntry, goalWeightKg *float64) *WeightTrend {
+	trend := &WeightTrend{Points: []WeightTrendPoint{}, GoalWeightKg: goalWeightKg}
+	if len(entries) == 0 {
+		return trend
+	}
+
+	value := entries[0].WeightKg
+	for i, entry := range entries {
+		if i > 0 {
+			days := entries[i-1].Date.DaysUntil(entry.Date)
+			alpha := 1 - math.Pow(1-weightTrendAlpha, float64(max(days, 1)))
+			value += alpha * (entry.WeightKg - value)
+		}
+		trend.Points = append(trend.Points, WeightTrendPoint{
+			Date:     entry.Date,
+			WeightKg: entry.WeightKg,
+			TrendKg:  math.Round(value*100) / 100,
+		})
+	}
+
+	last := trend.Points[len(trend.Points)-1]
+	trend.CurrentTrend = &last.TrendKg
+
+	rate, ok := weeklyRate(trend.Points)
+	if !ok {
+		return trend
+	}
+	trend.WeeklyRate = &rate
+
+	if goalWeightKg != nil && rate != 0 {
+		days := (*goalWeightKg - last.TrendKg) / (rate / 7)
+		if days >= 0 && days < 365*5 {
+			date := last.Date.AddDays(int(math.Ceil(days)))
+			trend.ProjectedDate = &date
+		}
+	}
+	return trend
+}
+
+// Наклон тренда (метод наименьших квадратов) за последние weightRateWindow дней, кг в неделю
+func weeklyRate(points []WeightTrendPoint) (float64, bool) {
+	last := points[len(points)-1].Date
+
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := float64(p.Date.DaysUntil(last))
+		if x > weightRateWindow {
+			continue
+		}
+		x = -x
+		n++
+		sumX += x
+		sumY += p.TrendKg
+		sumXY += x * p.TrendKg
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return math.Round(slope*7*100) / 100, true
+}
@@ -0,0 +1,65 @@
+package models
+
+import "strings"
+
+// Способы приготовления, для которых известен выход готового продукта
+const (
+	PREP_BOILED  = "boiled"  // варка
+	PREP_FRIED   = "fried"   // жарка
+	PREP_BAKED   = "baked"   // запекание
+	PREP_GRILLED = "grilled" // гриль
+	PREP_STEWED  = "stewed"  // тушение
+	PREP_STEAMED = "steamed" // на пару
+)
+
+// Выход готового продукта относительно сырого веса: макароны и крупы
+// впитывают воду, мясо и овощи теряют влагу
+var cookingYields = map[string]map[ProductCategory]float64{
+	PREP_BOILED: {
+		GRAIN:     2.2,
+		MEAT:      0.75,
+		FISH:      0.85,
+		VEGETABLE: 0.95,
+		EGG:       1.0,
+	},
+	PREP_FRIED: {
+		MEAT:      0.7,
+		FISH:      0.8,
+		VEGETABLE: 0.7,
+		EGG:       0.9,
+	},
+	PREP_BAKED: {
+		MEAT:      0.7,
+		FISH:      0.8,
+		VEGETABLE: 0.8,
+	},
+	PREP_GRILLED: {
+		MEAT:      0.7,
+		FISH:      0.8,
+		VEGETABLE: 0.75,
+	},
+	PREP_STEWED: {
+		MEAT:      0.75,
+		VEGETABLE: 0.85,
+		GRAIN:     2.0,
+	},
+	PREP_STEAMED: {
+		FISH:      0.9,
+		VEGETABLE: 0.95,
+		GRAIN:     2.0,
+	},
+}
+
+// Во сколько раз меняется вес ингредиента при готовке. Заданный для
+// ингредиента коэффициент важнее таблицы; по умолчанию вес не меняется.
+// Product должен быть загружен
+func (i *Ingredient) yieldFactor() float64 {
+	if i.YieldFactor != nil && *i.YieldFactor > 0 {
+		return *i.YieldFactor
+	}
+	preparation := strings.ToLower(strings.TrimSpace(i.Preparation))
+	if factor, ok := cookingYields[preparation][i.Product.Category]; ok {
+		return factor
+	}
+	return 1.0
+}
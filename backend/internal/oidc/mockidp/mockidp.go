@@ -0,0 +1,239 @@
+// Пакет mockidp - минимальный OpenID Connect провайдер для локальной
+// проверки входа и тестов. Не использовать в продакшене: пускает любого
+// пользователя без пароля.
+package mockidp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	subject       string
+	email         string
+	name          string
+	expiresAt     time.Time
+}
+
+// Провайдер с одним RSA-ключом. Issuer можно задать после создания,
+// например когда адрес httptest-сервера стал известен
+type Server struct {
+	Issuer string
+	key    *rsa.PrivateKey
+	mux    *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+// Идентификатор ключа в JWKS и заголовке токена
+const KeyID = "mock"
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock IdP</title>
+<form method="post">
+  {{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">{{end}}
+  <p><label>Subject <input name="sub" value="mock-user" required></label></p>
+  <p><label>Email <input name="email" value="mock-user@example.com"></label></p>
+  <p><label>Name <input name="name" value="Mock User"></label></p>
+  <button>Sign in</button>
+</form>`))
+
+func New(issuer string, key *rsa.PrivateKey) *Server {
+	s := &Server{Issuer: issuer, key: key, codes: map[string]authCode{}}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc("GET /jwks", s.jwks)
+	s.mux.HandleFunc("GET /authorize", s.authorizeForm)
+	s.mux.HandleFunc("POST /authorize", s.authorize)
+	s.mux.HandleFunc("POST /token", s.token)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": KeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := loginPage.Execute(w, r.URL.Query()); err != nil {
+		log.Println("failed to render login page:", err)
+	}
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = authCode{
+		clientID:      r.PostForm.Get("client_id"),
+		redirectURI:   r.PostForm.Get("redirect_uri"),
+		nonce:         r.PostForm.Get("nonce"),
+		codeChallenge: r.PostForm.Get("code_challenge"),
+		subject:       r.PostForm.Get("sub"),
+		email:         r.PostForm.Get("email"),
+		name:          r.PostForm.Get("name"),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	q := redirect.Query()
+	q.Set("code", code)
+	q.Set("state", r.PostForm.Get("state"))
+	redirect.RawQuery = q.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	s.mu.Lock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	clientID := r.PostForm.Get("client_id")
+	if id, _, basic := r.BasicAuth(); basic {
+		clientID, _ = url.QueryUnescape(id)
+	}
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type")
+		return
+	case !ok || time.Now().After(code.expiresAt):
+		tokenError(w, "invalid_grant")
+		return
+	case code.clientID != clientID || code.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, "invalid_grant")
+		return
+	case code.codeChallenge != "" && code.codeChallenge != base64.RawURLEncoding.EncodeToString(challenge[:]):
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := s.Sign(map[string]any{
+		"iss":            s.Issuer,
+		"sub":            code.subject,
+		"aud":            code.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.email,
+		"email_verified": code.email != "",
+		"name":           code.name,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// Подписать ID-токен ключом провайдера (RS256)
+func (s *Server) Sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": KeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("failed to write response:", err)
+	}
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalln("failed to read random bytes:", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
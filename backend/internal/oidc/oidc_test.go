@@ -0,0 +1,224 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/oidc"
+	"github.com/cr1phy/fitly/internal/oidc/mockidp"
+)
+
+const (
+	clientID    = "fitly"
+	redirectURL = "http://localhost:8080/auth/callback"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+// Поднять mock IdP на httptest-сервере
+func startIdP(t *testing.T) (*mockidp.Server, *httptest.Server) {
+	t.Helper()
+	idp := mockidp.New("", generateKey(t))
+	ts := httptest.NewServer(idp)
+	t.Cleanup(ts.Close)
+	idp.Issuer = ts.URL
+	return idp, ts
+}
+
+func newProvider(t *testing.T, issuer string) *oidc.Provider {
+	t.Helper()
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:   issuer,
+		ClientID:    clientID,
+		RedirectURL: redirectURL,
+	})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	return p
+}
+
+// Пройти страницу входа IdP и вернуть код авторизации из редиректа
+func authorize(t *testing.T, authURL, subject string) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	form := u.Query()
+	form.Set("sub", subject)
+	form.Set("email", subject+"@example.com")
+	form.Set("name", "Test User")
+	u.RawQuery = ""
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.PostForm(u.String(), form)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestDiscovery(t *testing.T) {
+	idp, ts := startIdP(t)
+	p := newProvider(t, ts.URL)
+
+	if p.Issuer() != ts.URL {
+		t.Errorf("Issuer() = %q, want %q", p.Issuer(), ts.URL)
+	}
+
+	authURL, err := url.Parse(p.AuthCodeURL("state", "nonce", "verifier"))
+	if err != nil {
+		t.Fatalf("parse auth url: %v", err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != ts.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %q, want %q", got, ts.URL+"/authorize")
+	}
+	q := authURL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Errorf("auth url has no S256 code challenge: %s", authURL)
+	}
+	if q.Get("client_id") != clientID || q.Get("redirect_uri") != redirectURL {
+		t.Errorf("auth url has wrong client: %s", authURL)
+	}
+
+	idp.Issuer = "http://issuer.example"
+	if _, err := oidc.NewProvider(context.Background(), oidc.Config{IssuerURL: ts.URL, ClientID: clientID, RedirectURL: redirectURL}); err == nil {
+		t.Error("NewProvider accepted a discovery document with another issuer")
+	}
+}
+
+func TestExchangeWithPKCE(t *testing.T) {
+	_, ts := startIdP(t)
+	p := newProvider(t, ts.URL)
+	ctx := context.Background()
+
+	code, state := authorize(t, p.AuthCodeURL("state-1", "nonce-1", "verifier-1"), "alice")
+	if state != "state-1" {
+		t.Errorf("state = %q, want %q", state, "state-1")
+	}
+
+	claims, err := p.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "alice" || claims.Email != "alice@example.com" || claims.Issuer != ts.URL {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Код одноразовый
+	if _, err := p.Exchange(ctx, code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("Exchange accepted a code that was already used")
+	}
+
+	// Без правильного verifier код не обменять
+	code, _ = authorize(t, p.AuthCodeURL("state-2", "nonce-2", "verifier-2"), "alice")
+	if _, err := p.Exchange(ctx, code, "wrong-verifier", "nonce-2"); err == nil {
+		t.Error("Exchange accepted a wrong PKCE verifier")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	_, ts := startIdP(t)
+	p := newProvider(t, ts.URL)
+
+	code, _ := authorize(t, p.AuthCodeURL("state", "nonce", "verifier"), "alice")
+	if _, err := p.Exchange(context.Background(), code, "verifier", "another-nonce"); err == nil {
+		t.Error("Exchange accepted an id token with another nonce")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	idp, ts := startIdP(t)
+	p := newProvider(t, ts.URL)
+	other := mockidp.New(ts.URL, generateKey(t))
+
+	claims := func(change func(map[string]any)) map[string]any {
+		now := time.Now()
+		c := map[string]any{
+			"iss": ts.URL,
+			"sub": "alice",
+			"aud": clientID,
+			"iat": now.Unix(),
+			"exp": now.Add(5 * time.Minute).Unix(),
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		signer  *mockidp.Server
+		claims  map[string]any
+		tamper  func(string) string
+		wantErr string
+	}{
+		{name: "valid", signer: idp, claims: claims(nil)},
+		{name: "audience list", signer: idp, claims: claims(func(c map[string]any) { c["aud"] = []string{"other", clientID} })},
+		{name: "bad signature", signer: other, claims: claims(nil), wantErr: "signature"},
+		{name: "tampered payload", signer: idp, claims: claims(nil), tamper: func(token string) string {
+			parts := strings.Split(token, ".")
+			forged, err := other.Sign(claims(func(c map[string]any) { c["sub"] = "mallory" }))
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			return parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+		}, wantErr: "signature"},
+		{name: "wrong audience", signer: idp, claims: claims(func(c map[string]any) { c["aud"] = "other-client" }), wantErr: "audience"},
+		{name: "wrong issuer", signer: idp, claims: claims(func(c map[string]any) { c["iss"] = "http://issuer.example" }), wantErr: "issuer"},
+		{name: "expired", signer: idp, claims: claims(func(c map[string]any) {
+			c["iat"] = time.Now().Add(-time.Hour).Unix()
+			c["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}), wantErr: "expired"},
+		{name: "missing subject", signer: idp, claims: claims(func(c map[string]any) { delete(c, "sub") }), wantErr: "subject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.Sign(tt.claims)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+			if tt.tamper != nil {
+				token = tt.tamper(token)
+			}
+
+			_, err = p.Verify(context.Background(), token)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Verify: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("Verify accepted the token, want error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("Verify error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
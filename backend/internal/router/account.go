@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type preferencesRequest struct {
+	UnitSystem *models.UnitSystem `json:"unit_system"`
+}
+
+func updateMe(c *gin.Context) {
+	var req preferencesRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if req.UnitSystem != nil {
+		if err := models.ValidateUnitSystem(*req.UnitSystem); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		user.UnitSystem = *req.UnitSystem
+	}
+
+	if err := models.DB.Model(user).Select("unit_system").Updates(user).Error; err != nil {
+		log.Println("something went wrong with updating user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// Система единиц для ответа: ?units= важнее настройки пользователя
+func unitSystem(c *gin.Context) (models.UnitSystem, bool) {
+	if units := c.Query("units"); units != "" {
+		system := models.UnitSystem(units)
+		if err := models.ValidateUnitSystem(system); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return "", false
+		}
+		return system, true
+	}
+	if user := currentUser(c); user != nil && user.UnitSystem != "" {
+		return user.UnitSystem, true
+	}
+	return models.METRIC, true
+}
+
+func exportAccount(c *gin.Context) {
+	user := currentUser(c)
+
+	export, err := models.ExportUserData(user.ID)
+	if err != nil {
+		log.Println("something went wrong with exporting user data:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	filename := fmt.Sprintf("fitly-export-%d-%s.json", user.ID, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+func deleteAccount(c *gin.Context) {
+	if err := models.DeleteUser(currentUser(c).ID); err != nil {
+		log.Println("something went wrong with deleting user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
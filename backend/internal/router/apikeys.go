@@ -0,0 +1,67 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type createAPIKeyRequest struct {
+	Name   string            `json:"name" binding:"required"`
+	Scopes []models.APIScope `json:"scopes" binding:"required"`
+}
+
+func getAPIKeys(c *gin.Context) {
+	result := []models.APIKey{}
+	models.DB.Where("user_id = ?", currentUser(c).ID).Order("id").Find(&result)
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": result})
+}
+
+func createAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	k, key, err := models.NewAPIKey(currentUser(c).ID, req.Name, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := models.DB.Create(k).Error; err != nil {
+		log.Println("something went wrong with creating api key:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": k, "key": key})
+}
+
+func revokeAPIKey(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var k models.APIKey
+	if err := models.DB.Where("user_id = ?", currentUser(c).ID).First(&k, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+
+	if k.IsActive() {
+		now := time.Now()
+		k.RevokedAt = &now
+		if err := models.DB.Model(&k).Update("revoked_at", now).Error; err != nil {
+			log.Println("something went wrong with revoking api key:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, k)
+}
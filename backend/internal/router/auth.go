@@ -0,0 +1,148 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	userKey         = "user"
+	sessionKey      = "session"
+	apiKeyKey       = "api_key"
+	scopeGrantedKey = "scope_granted"
+)
+
+func authenticate(c *gin.Context) {
+	token, ok := bearerToken(c)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(token, models.APIKeyPrefix):
+		authenticateAPIKey(c, token)
+	case strings.HasPrefix(token, models.SessionTokenPrefix):
+		authenticateSession(c, token)
+	default:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+	}
+}
+
+func authenticateSession(c *gin.Context, token string) {
+	var s models.Session
+	if err := models.DB.Where("token_hash = ?", models.HashToken(token)).First(&s).Error; err != nil || !s.IsAccessValid() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+		return
+	}
+
+	var u models.User
+	if err := models.DB.First(&u, s.UserID).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+		return
+	}
+
+	models.DB.Model(&s).UpdateColumn("last_used_at", time.Now())
+
+	c.Set(userKey, &u)
+	c.Set(sessionKey, &s)
+	c.Next()
+}
+
+func authenticateAPIKey(c *gin.Context, token string) {
+	var k models.APIKey
+	if err := models.DB.Where("key_hash = ? AND revoked_at IS NULL", models.HashToken(token)).First(&k).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+		return
+	}
+
+	var u models.User
+	if err := models.DB.First(&u, k.UserID).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid token"})
+		return
+	}
+
+	models.DB.Model(&k).UpdateColumns(map[string]any{
+		"last_used_at": time.Now(),
+		"usage_count":  gorm.Expr("usage_count + 1"),
+	})
+
+	c.Set(userKey, &u)
+	c.Set(apiKeyKey, &k)
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	return token, ok && token != ""
+}
+
+func currentUser(c *gin.Context) *models.User {
+	if u, ok := c.Get(userKey); ok {
+		return u.(*models.User)
+	}
+	return nil
+}
+
+func currentSession(c *gin.Context) *models.Session {
+	if s, ok := c.Get(sessionKey); ok {
+		return s.(*models.Session)
+	}
+	return nil
+}
+
+func currentAPIKey(c *gin.Context) *models.APIKey {
+	if k, ok := c.Get(apiKeyKey); ok {
+		return k.(*models.APIKey)
+	}
+	return nil
+}
+
+// Запросы с API-ключом допускаются только к маршрутам, явно разрешенным через requireScope
+func apiKeyAllowed(c *gin.Context) bool {
+	return currentAPIKey(c) == nil || c.GetBool(scopeGrantedKey)
+}
+
+func requireScope(scope models.APIScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if k := currentAPIKey(c); k != nil {
+			if !k.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "API key lacks scope " + string(scope)})
+				return
+			}
+			c.Set(scopeGrantedKey, true)
+		}
+		c.Next()
+	}
+}
+
+func requireUser(c *gin.Context) {
+	if currentUser(c) == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return
+	}
+	if !apiKeyAllowed(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+		return
+	}
+	c.Next()
+}
+
+func requireEditor(c *gin.Context) {
+	u := currentUser(c)
+	if u == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return
+	}
+	if !u.IsEditor() || currentAPIKey(c) != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+		return
+	}
+	c.Next()
+}
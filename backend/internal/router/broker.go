@@ -0,0 +1,84 @@
+package router
+
+import (
+	"sync"
+)
+
+// Событие для подписчиков списка покупок
+type listEvent struct {
+	Type string
+	Data any
+}
+
+// Рассылка событий клиентам, подключенным через SSE.
+// Работает в пределах одного экземпляра API
+type listBroker struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan listEvent]uint // канал -> пользователь
+}
+
+// Размер буфера подписчика: медленный клиент пропускает события, а не тормозит остальных
+const subscriberBuffer = 16
+
+var shoppingListEvents = &listBroker{subscribers: map[uint]map[chan listEvent]uint{}}
+
+func (b *listBroker) subscribe(listID, userID uint) chan listEvent {
+	ch := make(chan listEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[listID] == nil {
+		b.subscribers[listID] = map[chan listEvent]uint{}
+	}
+	b.subscribers[listID][ch] = userID
+	return ch
+}
+
+func (b *listBroker) unsubscribe(listID uint, ch chan listEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[listID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[listID], ch)
+	if len(b.subscribers[listID]) == 0 {
+		delete(b.subscribers, listID)
+	}
+	close(ch)
+}
+
+func (b *listBroker) publish(listID uint, eventType string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[listID] {
+		select {
+		case ch <- listEvent{Type: eventType, Data: data}:
+		default:
+		}
+	}
+}
+
+// Отключить всех подписчиков, например после удаления списка
+func (b *listBroker) close(listID uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[listID] {
+		close(ch)
+	}
+	delete(b.subscribers, listID)
+}
+
+// Отключить подписки пользователя, например после удаления его из участников
+func (b *listBroker) closeUser(listID, userID uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, subscriber := range b.subscribers[listID] {
+		if subscriber == userID {
+			close(ch)
+			delete(b.subscribers[listID], ch)
+		}
+	}
+	if len(b.subscribers[listID]) == 0 {
+		delete(b.subscribers, listID)
+	}
+}
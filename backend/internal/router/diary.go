@@ -0,0 +1,170 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type diaryEntryRequest struct {
+	Date      *models.Date    `json:"date"`
+	Slot      models.MealSlot `json:"slot" binding:"required"`
+	ProductID *uint           `json:"product_id"`
+	DishID    *uint           `json:"dish_id"`
+	Amount    float64         `json:"amount"`
+	Unit      models.Unit     `json:"unit"`
+	Servings  float64         `json:"servings"`
+	Cooked    bool            `json:"cooked"` // блюдо приготовлено дома: списать ингредиенты из запасов
+}
+
+// Дата из ?date=, по умолчанию сегодня
+func queryDate(c *gin.Context, key string) (models.Date, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return models.Today(), true
+	}
+	date, err := models.ParseDate(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return models.Date{}, false
+	}
+	return date, true
+}
+
+func loadDiary(userID uint, date models.Date) []models.DiaryEntry {
+	entries := []models.DiaryEntry{}
+	models.DB.Scopes(models.PreloadProduct("Product"), models.PreloadProduct("Dish.Ingredients.Product")).
+		Where("user_id = ? AND date = ?", userID, date).
+		Order("created_at").Find(&entries)
+	return entries
+}
+
+func getDiary(c *gin.Context) {
+	date, ok := queryDate(c, "date")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"date": date, "entries": loadDiary(currentUser(c).ID, date)})
+}
+
+func getDiarySummary(c *gin.Context) {
+	date, ok := queryDate(c, "date")
+	if !ok {
+		return
+	}
+	user := currentUser(c)
+	summary := models.SummarizeDiary(date, loadDiary(user.ID, date))
+	summary.ApplyExercise(loadExercise(user.ID, date))
+
+	profile, err := loadProfile(user.ID)
+	if err != nil {
+		log.Println("something went wrong with loading profile:", err)
+	}
+	if profile != nil {
+		if targets, err := profile.Targets(); err == nil {
+			summary.ApplyTargets(targets)
+		}
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+func addDiaryEntry(c *gin.Context) {
+	var req diaryEntryRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	entry := models.DiaryEntry{
+		UserID:    user.ID,
+		Date:      models.Today(),
+		Slot:      req.Slot,
+		ProductID: req.ProductID,
+		DishID:    req.DishID,
+		Amount:    req.Amount,
+		Unit:      req.Unit,
+		Servings:  req.Servings,
+	}
+	if req.Date != nil {
+		entry.Date = *req.Date
+	}
+	if entry.Unit == "" && entry.Servings == 0 {
+		entry.Unit = models.GRAM
+	}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if entry.ProductID != nil {
+		var p models.Product
+		if err := models.DB.Scopes(models.PreloadProduct("")).First(&p, *entry.ProductID).Error; err != nil || !p.IsVisibleTo(user) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Product not found"})
+			return
+		}
+		if _, err := p.Convert(entry.Amount, entry.Unit); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	} else {
+		var d models.Dish
+		if err := models.DB.Scopes(models.PreloadProduct("Ingredients.Product")).First(&d, *entry.DishID).Error; err != nil || !d.IsVisibleTo(user) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Dish not found"})
+			return
+		}
+		entry.Dish = &d
+	}
+
+	var factor float64
+	if req.Cooked {
+		if entry.Dish == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Only dish entries can be cooked"})
+			return
+		}
+		var err error
+		if factor, err = entry.DishFactor(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Product", "Dish").Create(&entry).Error; err != nil {
+			return err
+		}
+		if req.Cooked {
+			return consumeDishFromPantry(tx, user.ID, entry.Dish, factor)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("something went wrong with creating diary entry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	entry.Dish = nil
+	c.JSON(http.StatusCreated, entry)
+}
+
+func deleteDiaryEntry(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("user_id = ?", currentUser(c).ID).Delete(&models.DiaryEntry{}, id)
+	if result.Error != nil {
+		log.Println("something went wrong with deleting diary entry:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
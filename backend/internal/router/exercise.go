@@ -0,0 +1,130 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type exerciseEntryRequest struct {
+	Date        *models.Date `json:"date"`
+	ActivityID  uint         `json:"activity_id" binding:"required"`
+	DurationMin float64      `json:"duration_min" binding:"required"`
+	WeightKg    float64      `json:"weight_kg"` // по умолчанию вес из профиля
+	Notes       string       `json:"notes"`
+}
+
+func getActivities(c *gin.Context) {
+	filter := c.Query("filter")
+
+	result := []models.Activity{}
+	models.DB.Where("name LIKE ?", "%"+filter+"%").Order("name").Find(&result)
+
+	c.JSON(http.StatusOK, gin.H{"activities": result})
+}
+
+func addActivity(c *gin.Context) {
+	var a models.Activity
+	if err := c.ShouldBindBodyWithJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	a.ID = 0
+	if err := a.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := models.DB.Create(&a).Error; err != nil {
+		log.Println("something went wrong with creating activity:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, a)
+}
+
+func loadExercise(userID uint, date models.Date) []models.ExerciseEntry {
+	entries := []models.ExerciseEntry{}
+	models.DB.Preload("Activity").
+		Where("user_id = ? AND date = ?", userID, date).
+		Order("created_at").Find(&entries)
+	return entries
+}
+
+func getExercise(c *gin.Context) {
+	date, ok := queryDate(c, "date")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"date": date, "entries": loadExercise(currentUser(c).ID, date)})
+}
+
+func addExerciseEntry(c *gin.Context) {
+	var req exerciseEntryRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	entry := models.ExerciseEntry{
+		UserID:      user.ID,
+		Date:        models.Today(),
+		ActivityID:  req.ActivityID,
+		DurationMin: req.DurationMin,
+		WeightKg:    req.WeightKg,
+		Notes:       req.Notes,
+	}
+	if req.Date != nil {
+		entry.Date = *req.Date
+	}
+
+	var a models.Activity
+	if err := models.DB.First(&a, req.ActivityID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Activity not found"})
+		return
+	}
+	entry.Activity = &a
+
+	if entry.WeightKg == 0 {
+		profile, err := loadProfile(user.ID)
+		if err != nil {
+			log.Println("something went wrong with loading profile:", err)
+		}
+		if profile != nil {
+			entry.WeightKg = profile.WeightKg
+		}
+	}
+
+	if err := entry.Calculate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.DB.Omit("Activity").Create(&entry).Error; err != nil {
+		log.Println("something went wrong with creating exercise entry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+func deleteExerciseEntry(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("user_id = ?", currentUser(c).ID).Delete(&models.ExerciseEntry{}, id)
+	if result.Error != nil {
+		log.Println("something went wrong with deleting exercise entry:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
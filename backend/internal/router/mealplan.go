@@ -0,0 +1,331 @@
+package router
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type mealPlanRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type mealPlanEntryRequest struct {
+	Date     models.Date     `json:"date" binding:"required"`
+	Slot     models.MealSlot `json:"slot" binding:"required"`
+	DishID   uint            `json:"dish_id" binding:"required"`
+	Servings float64         `json:"servings"`
+}
+
+type copyWeekRequest struct {
+	Week    *models.Date `json:"week"`    // любая дата целевой недели, по умолчанию текущая
+	Replace bool         `json:"replace"` // очистить целевую неделю перед копированием
+}
+
+// План текущего пользователя из :id, иначе 404
+func ownMealPlan(c *gin.Context) (*models.MealPlan, bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	var p models.MealPlan
+	if err := models.DB.Where("user_id = ?", currentUser(c).ID).First(&p, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return nil, false
+	}
+	return &p, true
+}
+
+func getMealPlans(c *gin.Context) {
+	result := []models.MealPlan{}
+	models.DB.Where("user_id = ?", currentUser(c).ID).Order("id").Find(&result)
+
+	c.JSON(http.StatusOK, gin.H{"meal_plans": result})
+}
+
+func createMealPlan(c *gin.Context) {
+	var req mealPlanRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	p := models.MealPlan{UserID: currentUser(c).ID, Name: req.Name}
+	if err := p.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.DB.Create(&p).Error; err != nil {
+		log.Println("something went wrong with creating meal plan:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+func getMealPlan(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	query := models.DB.Where("meal_plan_id = ?", p.ID)
+	if from := c.Query("from"); from != "" {
+		date, err := models.ParseDate(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		query = query.Where("date >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := models.ParseDate(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		query = query.Where("date <= ?", date)
+	}
+	// Блюдо, которое автор успел скрыть, в плане не показывается
+	err := query.Preload("Dish", models.VisibleDishes(currentUser(c))).Order("date, id").Find(&p.Entries).Error
+	if err != nil {
+		log.Println("something went wrong with loading meal plan entries:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+func updateMealPlan(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	var req mealPlanRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	p.Name = req.Name
+	if err := p.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.DB.Save(p).Error; err != nil {
+		log.Println("something went wrong with updating meal plan:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func deleteMealPlan(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("meal_plan_id = ?", p.ID).Delete(&models.MealPlanEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(p).Error
+	})
+	if err != nil {
+		log.Println("something went wrong with deleting meal plan:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+// Проверить и заполнить запись плана из запроса
+func bindMealPlanEntry(c *gin.Context, entry *models.MealPlanEntry) bool {
+	var req mealPlanEntryRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return false
+	}
+
+	entry.Date = req.Date
+	entry.Slot = req.Slot
+	entry.DishID = req.DishID
+	entry.Servings = req.Servings
+	if entry.Servings == 0 {
+		entry.Servings = 1
+	}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return false
+	}
+
+	var d models.Dish
+	if err := models.DB.First(&d, entry.DishID).Error; err != nil || !d.IsVisibleTo(currentUser(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Dish not found"})
+		return false
+	}
+	return true
+}
+
+func addMealPlanEntry(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	entry := models.MealPlanEntry{MealPlanID: p.ID}
+	if !bindMealPlanEntry(c, &entry) {
+		return
+	}
+	if err := models.DB.Create(&entry).Error; err != nil {
+		log.Println("something went wrong with creating meal plan entry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+func updateMealPlanEntry(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	entryID, ok := paramID(c, "entry_id")
+	if !ok {
+		return
+	}
+
+	var entry models.MealPlanEntry
+	if err := models.DB.Where("meal_plan_id = ?", p.ID).First(&entry, entryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	if !bindMealPlanEntry(c, &entry) {
+		return
+	}
+	if err := models.DB.Save(&entry).Error; err != nil {
+		log.Println("something went wrong with updating meal plan entry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func deleteMealPlanEntry(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	entryID, ok := paramID(c, "entry_id")
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("meal_plan_id = ?", p.ID).Delete(&models.MealPlanEntry{}, entryID)
+	if result.Error != nil {
+		log.Println("something went wrong with deleting meal plan entry:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+func loadMealPlanWeek(planID uint, weekStart models.Date) []models.MealPlanEntry {
+	entries := []models.MealPlanEntry{}
+	models.DB.Scopes(models.PreloadProduct("Dish.Ingredients.Product")).
+		Where("meal_plan_id = ? AND date >= ? AND date < ?", planID, weekStart, weekStart.AddDays(7)).
+		Order("date, id").Find(&entries)
+	return entries
+}
+
+func getMealPlanDay(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+	date, ok := queryDate(c, "date")
+	if !ok {
+		return
+	}
+
+	entries := []models.MealPlanEntry{}
+	models.DB.Scopes(models.PreloadProduct("Dish.Ingredients.Product")).
+		Where("meal_plan_id = ? AND date = ?", p.ID, date).
+		Order("id").Find(&entries)
+
+	week := models.SummarizeMealPlanWeek(date, entries)
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "nutrition": week.Days[0]})
+}
+
+func getMealPlanWeek(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+	date, ok := queryDate(c, "date")
+	if !ok {
+		return
+	}
+
+	weekStart := models.WeekStart(date)
+	c.JSON(http.StatusOK, models.SummarizeMealPlanWeek(weekStart, loadMealPlanWeek(p.ID, weekStart)))
+}
+
+func copyLastWeek(c *gin.Context) {
+	p, ok := ownMealPlan(c)
+	if !ok {
+		return
+	}
+
+	// Все поля необязательны, поэтому пустое тело - это значения по умолчанию
+	var req copyWeekRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	target := models.Today()
+	if req.Week != nil {
+		target = *req.Week
+	}
+	weekStart := models.WeekStart(target)
+
+	entries := []models.MealPlanEntry{}
+	models.DB.Where("meal_plan_id = ? AND date >= ? AND date < ?", p.ID, weekStart.AddDays(-7), weekStart).
+		Order("date, id").Find(&entries)
+	if len(entries) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Last week is empty"})
+		return
+	}
+	copies := models.ShiftMealPlanEntries(entries, 7)
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Replace {
+			err := tx.Where("meal_plan_id = ? AND date >= ? AND date < ?", p.ID, weekStart, weekStart.AddDays(7)).
+				Delete(&models.MealPlanEntry{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&copies).Error
+	})
+	if err != nil {
+		log.Println("something went wrong with copying meal plan week:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"week_start": weekStart, "entries": copies})
+}
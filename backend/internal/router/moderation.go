@@ -0,0 +1,58 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type reviewRequest struct {
+	Status models.SubmissionStatus `json:"status" binding:"required"`
+	Notes  string                  `json:"notes"`
+}
+
+func getModerationQueue(c *gin.Context) {
+	status := models.SubmissionStatus(c.DefaultQuery("status", string(models.PENDING)))
+	if err := models.ValidateSubmissionStatus(status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	result := []models.Product{}
+	models.DB.Where("status = ?", status).Order("id").Find(&result)
+
+	c.JSON(http.StatusOK, gin.H{"products": result})
+}
+
+func reviewProduct(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	var p models.Product
+	if err := models.DB.First(&p, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+
+	if err := p.Review(currentUser(c), req.Status, req.Notes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := models.DB.Save(&p).Error; err != nil {
+		log.Println("something went wrong with reviewing product:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
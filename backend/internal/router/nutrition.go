@@ -0,0 +1,71 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type nutritionItem struct {
+	ProductID uint        `json:"product_id" binding:"required"`
+	Amount    float64     `json:"amount" binding:"required,gt=0"`
+	Unit      models.Unit `json:"unit"`
+
+	Basis models.AmountBasis `json:"basis"` // edible по умолчанию
+}
+
+type nutritionRequest struct {
+	Items []nutritionItem `json:"items" binding:"required,min=1,dive"`
+}
+
+type nutritionItemResult struct {
+	nutritionItem
+	Nutrition *models.NutritionInfo `json:"nutrition,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func calculateNutrition(c *gin.Context) {
+	var req nutritionRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	total := models.NutritionInfo{}
+	items := make([]nutritionItemResult, 0, len(req.Items))
+
+	for _, item := range req.Items {
+		if item.Unit == "" {
+			item.Unit = models.GRAM
+		}
+		result := nutritionItemResult{nutritionItem: item}
+
+		var p models.Product
+		if err := models.DB.Scopes(models.PreloadProduct("")).First(&p, item.ProductID).Error; err != nil || !p.IsVisibleTo(user) {
+			result.Error = "product not found"
+			items = append(items, result)
+			continue
+		}
+
+		if err := models.ValidateAmountBasis(item.Basis); err != nil {
+			result.Error = err.Error()
+			items = append(items, result)
+			continue
+		}
+
+		nutrition, err := p.CalculateNutritionAs(item.Amount, item.Unit, item.Basis)
+		if err != nil {
+			result.Error = err.Error()
+			items = append(items, result)
+			continue
+		}
+
+		result.Nutrition = nutrition
+		total.Add(*nutrition)
+		items = append(items, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"total": total, "items": items})
+}
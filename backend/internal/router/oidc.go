@@ -0,0 +1,125 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/cr1phy/fitly/internal/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie с state, nonce и PKCE verifier на время входа
+const (
+	loginCookie       = "fitly_oidc"
+	loginCookieMaxAge = 10 * 60
+)
+
+var (
+	providerMu sync.Mutex
+	provider   *oidc.Provider
+)
+
+// Провайдер создается при первом входе, чтобы недоступный IdP не мешал старту API
+func loginProvider(c *gin.Context) (*oidc.Provider, error) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+
+	if provider != nil {
+		return provider, nil
+	}
+
+	config, _ := oidc.ConfigFromEnv()
+	p, err := oidc.NewProvider(c.Request.Context(), config)
+	if err != nil {
+		return nil, err
+	}
+	provider = p
+	return provider, nil
+}
+
+func login(c *gin.Context) {
+	p, err := loginProvider(c)
+	if err != nil {
+		log.Println("something went wrong with oidc provider:", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "Identity provider is unavailable"})
+		return
+	}
+
+	values := make([]string, 3)
+	for i := range values {
+		if values[i], err = oidc.RandomString(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+			return
+		}
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginCookie, strings.Join(values, "."), loginCookieMaxAge, "/auth", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, p.AuthCodeURL(state, nonce, verifier))
+}
+
+func loginCallback(c *gin.Context) {
+	cookie, err := c.Cookie(loginCookie)
+	c.SetCookie(loginCookie, "", -1, "/auth", "", c.Request.TLS != nil, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Login session expired"})
+		return
+	}
+
+	values := strings.Split(cookie, ".")
+	if len(values) != 3 || values[0] != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid state"})
+		return
+	}
+	nonce, verifier := values[1], values[2]
+
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Login failed: " + e})
+		return
+	}
+
+	p, err := loginProvider(c)
+	if err != nil {
+		log.Println("something went wrong with oidc provider:", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "Identity provider is unavailable"})
+		return
+	}
+
+	claims, err := p.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		log.Println("something went wrong with oidc login:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Login failed"})
+		return
+	}
+
+	email := claims.Email
+	if !claims.EmailVerified {
+		email = ""
+	}
+	user, err := models.FindOrCreateOIDCUser(p.Issuer(), claims.Subject, email, claims.Name)
+	if err != nil {
+		log.Println("something went wrong with linking oidc account:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	s, tokens, err := models.NewSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err == nil {
+		err = models.DB.Create(s).Error
+	}
+	if err != nil {
+		log.Println("something went wrong with creating session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens, "user": user})
+}
+
+func getMe(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
+}
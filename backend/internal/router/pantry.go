@@ -0,0 +1,279 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Горизонт "скоро испортится" по умолчанию, дней
+const defaultExpiringDays = 3
+
+// Сколько блюд предлагать для продуктов с истекающим сроком
+const expiringSuggestionsLimit = 10
+
+type pantryItemRequest struct {
+	ProductID uint         `json:"product_id" binding:"required"`
+	Quantity  float64      `json:"quantity" binding:"required"`
+	Unit      models.Unit  `json:"unit"`
+	ExpiresAt *models.Date `json:"expires_at"`
+}
+
+type consumeRequest struct {
+	Amount float64     `json:"amount" binding:"required"`
+	Unit   models.Unit `json:"unit"` // по умолчанию единица позиции
+}
+
+type cookRequest struct {
+	DishID   uint    `json:"dish_id" binding:"required"`
+	Servings float64 `json:"servings"` // по умолчанию как в рецепте
+}
+
+type dishSuggestion struct {
+	Dish             models.Dish `json:"dish"`
+	ExpiringProducts []uint      `json:"expiring_products"`
+}
+
+func loadPantry(db *gorm.DB, userID uint) ([]models.PantryItem, error) {
+	items := []models.PantryItem{}
+	err := db.Scopes(models.PreloadProduct("Product")).Where("user_id = ?", userID).
+		Order("expires_at NULLS LAST, id").Find(&items).Error
+	return items, err
+}
+
+// Сохранить позиции после списания: пустые удаляются
+func savePantryItems(tx *gorm.DB, items []models.PantryItem, touched []int) error {
+	for _, i := range touched {
+		item := &items[i]
+		if item.Quantity <= 0 {
+			if err := tx.Delete(item).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(item).Update("quantity", item.Quantity).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Списать из запасов ингредиенты приготовленного блюда. Dish.Ingredients.Product
+// должны быть загружены, factor - доля рецепта
+func consumeDishFromPantry(tx *gorm.DB, userID uint, dish *models.Dish, factor float64) error {
+	items, err := loadPantry(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	touched := map[int]bool{}
+	for i := range dish.Ingredients {
+		ingredient := &dish.Ingredients[i]
+		indexes, _ := models.ConsumePantry(items, &ingredient.Product, ingredient.PurchasedAmount()*factor, ingredient.Unit)
+		for _, index := range indexes {
+			touched[index] = true
+		}
+	}
+
+	indexes := make([]int, 0, len(touched))
+	for index := range touched {
+		indexes = append(indexes, index)
+	}
+	return savePantryItems(tx, items, indexes)
+}
+
+func getPantry(c *gin.Context) {
+	items, err := loadPantry(models.DB, currentUser(c).ID)
+	if err != nil {
+		log.Println("something went wrong with loading pantry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func addPantryItem(c *gin.Context) {
+	var req pantryItemRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	item := models.PantryItem{
+		UserID:    user.ID,
+		ProductID: req.ProductID,
+		Quantity:  req.Quantity,
+		Unit:      req.Unit,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if item.Unit == "" {
+		item.Unit = models.GRAM
+	}
+	if err := item.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var p models.Product
+	if err := models.DB.First(&p, req.ProductID).Error; err != nil || !p.IsVisibleTo(user) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Product not found"})
+		return
+	}
+
+	if err := models.DB.Create(&item).Error; err != nil {
+		log.Println("something went wrong with creating pantry item:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	item.Product = &p
+	c.JSON(http.StatusCreated, item)
+}
+
+func consumePantryItem(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req consumeRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var item models.PantryItem
+	if err := models.DB.Scopes(models.PreloadProduct("Product")).Where("user_id = ?", currentUser(c).ID).First(&item, id).Error; err != nil || item.Product == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	if req.Unit == "" {
+		req.Unit = item.Unit
+	}
+
+	items := []models.PantryItem{item}
+	touched, missing := models.ConsumePantry(items, item.Product, req.Amount, req.Unit)
+	if len(touched) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Unit is not compatible with this item"})
+		return
+	}
+	if err := savePantryItems(models.DB, items, touched); err != nil {
+		log.Println("something went wrong with consuming pantry item:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"item": items[0], "missing": missing})
+}
+
+func deletePantryItem(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("user_id = ?", currentUser(c).ID).Delete(&models.PantryItem{}, id)
+	if result.Error != nil {
+		log.Println("something went wrong with deleting pantry item:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+// Приготовить блюдо без записи в дневник: списать ингредиенты из запасов
+func cookDish(c *gin.Context) {
+	var req cookRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	var d models.Dish
+	if err := models.DB.Scopes(models.PreloadProduct("Ingredients.Product")).First(&d, req.DishID).Error; err != nil || !d.IsVisibleTo(user) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Dish not found"})
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		return consumeDishFromPantry(tx, user.ID, &d, servingsFactor(&d, req.Servings))
+	})
+	if err != nil {
+		log.Println("something went wrong with cooking dish:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	items, _ := loadPantry(models.DB, user.ID)
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// Продукты, которые скоро испортятся, и блюда, в которых их можно использовать
+func getExpiringPantry(c *gin.Context) {
+	days := defaultExpiringDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "days must be a non-negative number"})
+			return
+		}
+		days = parsed
+	}
+
+	user := currentUser(c)
+	horizon := models.Today().AddDays(days)
+
+	expiring := []models.PantryItem{}
+	models.DB.Preload("Product").
+		Where("user_id = ? AND expires_at IS NOT NULL AND expires_at <= ?", user.ID, horizon).
+		Order("expires_at, id").Find(&expiring)
+
+	productIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, item := range expiring {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+
+	suggestions := []dishSuggestion{}
+	if len(productIDs) > 0 {
+		dishIDs := models.DB.Model(&models.Ingredient{}).Select("dish_id").Where("product_id IN ?", productIDs)
+
+		dishes := []models.Dish{}
+		models.DB.Scopes(models.VisibleDishes(user), models.PreloadProduct("Ingredients.Product")).
+			Where("id IN (?)", dishIDs).Find(&dishes)
+
+		for _, d := range dishes {
+			suggestion := dishSuggestion{Dish: d}
+			used := map[uint]bool{}
+			for _, ingredient := range d.Ingredients {
+				if seen[ingredient.ProductID] && !used[ingredient.ProductID] {
+					used[ingredient.ProductID] = true
+					suggestion.ExpiringProducts = append(suggestion.ExpiringProducts, ingredient.ProductID)
+				}
+			}
+			suggestions = append(suggestions, suggestion)
+		}
+
+		// Сначала блюда, которые используют больше истекающих продуктов
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return len(suggestions[i].ExpiringProducts) > len(suggestions[j].ExpiringProducts)
+		})
+		if len(suggestions) > expiringSuggestionsLimit {
+			suggestions = suggestions[:expiringSuggestionsLimit]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": expiring, "suggestions": suggestions})
+}
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Идентификатор из пути запроса. Строку нельзя передавать в gorm как
+// условие: значение с пробелом попадет в запрос как SQL
+func paramID(c *gin.Context, name string) (uint, bool) {
+	return parseID(c, c.Param(name), name)
+}
+
+func parseID(c *gin.Context, value, name string) (uint, bool) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type generatePlanRequest struct {
+	Seed         int64                   `json:"seed"`
+	StartDate    *models.Date            `json:"start_date"`
+	Tolerance    float64                 `json:"tolerance"`
+	Restrictions models.DietRestrictions `json:"restrictions"`
+	Targets      *models.NutritionInfo   `json:"targets"` // по умолчанию нормы из профиля
+	Locked       []models.PlannedMeal    `json:"locked"`
+}
+
+func generateMealPlan(c *gin.Context) {
+	var req generatePlanRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	opts := models.PlanOptions{
+		Restrictions: req.Restrictions,
+		Tolerance:    req.Tolerance,
+		Seed:         req.Seed,
+		StartDate:    models.Today(),
+		Locked:       req.Locked,
+	}
+	if req.StartDate != nil {
+		opts.StartDate = *req.StartDate
+	}
+
+	if req.Targets != nil {
+		opts.Targets = *req.Targets
+	} else {
+		profile, err := loadProfile(user.ID)
+		if err != nil {
+			log.Println("something went wrong with loading profile:", err)
+		}
+		if profile == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Targets are required when the profile is not filled in"})
+			return
+		}
+		targets, err := profile.Targets()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		opts.Targets = targets.Nutrition()
+	}
+
+	dishes := []models.Dish{}
+	models.DB.Scopes(models.VisibleDishes(user), models.PreloadProduct("Ingredients.Product")).
+		Order("id").Find(&dishes)
+
+	plan, err := models.GenerateMealPlan(dishes, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,144 @@
+package router
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type profileRequest struct {
+	Sex      models.Sex           `json:"sex" binding:"required"`
+	Age      int                  `json:"age" binding:"required"`
+	HeightCm float64              `json:"height_cm" binding:"required"`
+	WeightKg float64              `json:"weight_kg" binding:"required"`
+	Activity models.ActivityLevel `json:"activity"`
+	Goal     models.Goal          `json:"goal"`
+	Formula  models.BMRFormula    `json:"formula"`
+
+	GoalWeightKg *float64 `json:"goal_weight_kg"`
+}
+
+type profileResponse struct {
+	Profile *models.Profile               `json:"profile"`
+	BMR     map[models.BMRFormula]float64 `json:"bmr"`
+	TDEE    map[models.BMRFormula]float64 `json:"tdee"`
+	Targets *models.MacroTargets          `json:"targets"`
+}
+
+// Профиль пользователя или nil, если он еще не заполнен
+func loadProfile(userID uint) (*models.Profile, error) {
+	var p models.Profile
+	err := models.DB.Where("user_id = ?", userID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func newProfileResponse(p *models.Profile) (*profileResponse, error) {
+	resp := &profileResponse{
+		Profile: p,
+		BMR:     map[models.BMRFormula]float64{},
+		TDEE:    map[models.BMRFormula]float64{},
+	}
+	for _, formula := range []models.BMRFormula{models.MIFFLIN_ST_JEOR, models.HARRIS_BENEDICT} {
+		bmr, err := p.BMR(formula)
+		if err != nil {
+			return nil, err
+		}
+		tdee, err := p.TDEE(formula)
+		if err != nil {
+			return nil, err
+		}
+		resp.BMR[formula] = bmr
+		resp.TDEE[formula] = tdee
+	}
+
+	targets, err := p.Targets()
+	if err != nil {
+		return nil, err
+	}
+	resp.Targets = targets
+	return resp, nil
+}
+
+func getProfile(c *gin.Context) {
+	p, err := loadProfile(currentUser(c).ID)
+	if err != nil {
+		log.Println("something went wrong with loading profile:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if p == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Profile is not filled in"})
+		return
+	}
+
+	resp, err := newProfileResponse(p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func updateProfile(c *gin.Context) {
+	var req profileRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	p, err := loadProfile(user.ID)
+	if err != nil {
+		log.Println("something went wrong with loading profile:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if p == nil {
+		p = &models.Profile{UserID: user.ID}
+	}
+
+	p.Sex = req.Sex
+	p.Age = req.Age
+	p.HeightCm = req.HeightCm
+	p.WeightKg = req.WeightKg
+	p.Activity = req.Activity
+	p.Goal = req.Goal
+	p.Formula = req.Formula
+	p.GoalWeightKg = req.GoalWeightKg
+	if p.Activity == "" {
+		p.Activity = models.ACTIVITY_SEDENTARY
+	}
+	if p.Goal == "" {
+		p.Goal = models.GOAL_MAINTAIN
+	}
+	if p.Formula == "" {
+		p.Formula = models.MIFFLIN_ST_JEOR
+	}
+
+	if err := p.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.DB.Save(p).Error; err != nil {
+		log.Println("something went wrong with saving profile:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	resp, err := newProfileResponse(p)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/cr1phy/fitly/internal/models"
+	"github.com/cr1phy/fitly/internal/oidc"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -17,19 +21,25 @@ func getProduct(c *gin.Context) {
 	filter := c.Query("filter")
 
 	result := []models.Product{}
-	models.DB.Model(&models.Product{}).Where("name LIKE ?", "%"+filter+"%").Group("id").Find(&result)
+	models.DB.Model(&models.Product{}).
+		Scopes(models.VisibleProducts(currentUser(c))).
+		Where("name LIKE ?", "%"+filter+"%").Group("id").Find(&result)
 
 	if len(result) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{"products": result})
 }
 
 func getProductById(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
 	var p models.Product
-	if err := models.DB.First(&p, id); err != nil {
+	if err := models.DB.Scopes(models.PreloadProduct("")).First(&p, id).Error; err != nil || !p.IsVisibleTo(currentUser(c)) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
 		return
 	}
@@ -39,72 +49,289 @@ func getProductById(c *gin.Context) {
 func addProduct(c *gin.Context) {
 	var p models.Product
 	if err := c.ShouldBindBodyWithJSON(&p); err != nil {
-		log.Panicln("something went wrong with body:", err)
+		log.Println("something went wrong with body:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
 		return
 	}
 
-	if err := models.DB.Create(&p); err != nil {
-		log.Panicln("something went wrong with creating product:", err)
+	user := currentUser(c)
+	p.ID = 0
+	p.Status = models.PENDING
+	p.SubmittedBy = &user.ID
+	p.ReviewedBy, p.ReviewedAt, p.ReviewNotes = nil, nil, ""
+
+	if err := models.ValidateRefusePercent(p.RefusePercent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := models.DB.Create(&p).Error; err != nil {
+		log.Println("something went wrong with creating product:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully created!"})
+	c.JSON(http.StatusOK, gin.H{"message": "Submitted for review", "product": p})
 }
 
 func getDishes(c *gin.Context) {
 	filter := c.Query("filter")
 
 	result := []models.Dish{}
-	models.DB.Model(&models.Dish{}).Where("name LIKE ?", "%"+filter+"%").Group("id").Find(&result)
+	models.DB.Model(&models.Dish{}).Scopes(models.VisibleDishes(currentUser(c))).
+		Where("name LIKE ?", "%"+filter+"%").Group("id").Find(&result)
 
 	if len(result) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
 	}
 	c.JSON(http.StatusOK, result)
 }
 
 func getDishById(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
 
 	var d models.Dish
-	if err := models.DB.First(&d, id); err != nil {
+	if err := models.DB.Scopes(models.PreloadProduct("Ingredients.Product")).First(&d, id).Error; err != nil || !d.IsVisibleTo(currentUser(c)) {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
 		return
 	}
-	c.JSON(http.StatusOK, d)
+
+	system, ok := unitSystem(c)
+	if !ok {
+		return
+	}
+
+	servings, grams := c.Query("servings"), c.Query("total_grams")
+	if servings == "" && grams == "" {
+		d.LocalizeAmounts(system)
+		c.JSON(http.StatusOK, d)
+		return
+	}
+	if servings != "" && grams != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Use either servings or total_grams"})
+		return
+	}
+
+	var scaled *models.ScaledDish
+	if servings != "" {
+		value, err := strconv.ParseFloat(servings, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "servings must be a number"})
+			return
+		}
+		if scaled, err = d.ScaleToServings(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	} else {
+		value, err := strconv.ParseFloat(grams, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "total_grams must be a number"})
+			return
+		}
+		if scaled, err = d.ScaleToWeight(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+	scaled.Dish.LocalizeAmounts(system)
+	c.JSON(http.StatusOK, scaled)
 }
 
 func addDish(c *gin.Context) {
 	var d models.Dish
 	if err := c.ShouldBindBodyWithJSON(&d); err != nil {
-		log.Panicln("something went wrong with body:", err)
+		log.Println("something went wrong with body:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
 		return
 	}
 
-	if err := models.DB.Create(&d); err != nil {
-		log.Panicln("something went wrong with creating product:", err)
+	if errs := validateIngredients(c, d.Ingredients); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ingredients", "errors": errs})
+		return
+	}
+
+	// Владелец и идентификаторы задаются сервером, а не клиентом
+	d.ID = 0
+	d.UserID = &currentUser(c).ID
+	for i := range d.Ingredients {
+		d.Ingredients[i].ID = 0
+		d.Ingredients[i].DishID = 0
+	}
+
+	if err := models.DB.Create(&d).Error; err != nil {
+		log.Println("something went wrong with creating dish:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully created!"})
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully created!", "id": d.ID})
+}
+
+// Проверить ингредиенты: продукт виден пользователю, а единица для него
+// переводится в граммы. Ошибки привязаны к полям Ingredients[i] тела запроса
+func validateIngredients(c *gin.Context, ingredients []models.Ingredient) []models.FieldError {
+	user := currentUser(c)
+	var errs []models.FieldError
+	for i := range ingredients {
+		ingredient := &ingredients[i]
+		prefix := fmt.Sprintf("Ingredients[%d].", i)
+		if ingredient.Unit == "" {
+			ingredient.Unit = models.GRAM
+		}
+
+		var p models.Product
+		if err := models.DB.Scopes(models.PreloadProduct("")).First(&p, ingredient.ProductID).Error; err != nil || !p.IsVisibleTo(user) {
+			errs = append(errs, models.FieldError{Field: prefix + "ProductID", Message: "product not found"})
+			continue
+		}
+
+		ingredient.Product = p
+		for _, err := range ingredient.Validate() {
+			errs = append(errs, models.FieldError{Field: prefix + err.Field, Message: err.Message})
+		}
+		// Продукт нужен только для проверки, сохранять его заново не нужно
+		ingredient.Product = models.Product{}
+	}
+	return errs
+}
+
+// Формат лога запросов без строки запроса: в ней бывают одноразовые
+// билеты и другие секреты
+func logFormatter(param gin.LogFormatterParams) string {
+	path, _, _ := strings.Cut(param.Path, "?")
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		path,
+		param.ErrorMessage,
+	)
+}
+
+func corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	config.AddAllowHeaders("Authorization")
+	return config
 }
 
 func InitRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
 
-	r.Use(gin.Logger())
+	r.Use(gin.LoggerWithFormatter(logFormatter))
 	r.Use(gin.Recovery())
-	r.Use(cors.Default())
+	r.Use(cors.New(corsConfig()))
+	r.Use(authenticate)
 
 	r.GET("/", status)
-	r.GET("/products", getProduct)
-	r.GET("/product/:id", getProductById)
-	r.POST("/products", addProduct)
-	r.GET("/dishes", getDishes)
-	r.GET("/dish/:id", getDishById)
-	r.POST("/dishes", addDish)
+	read := requireScope(models.SCOPE_CATALOG_READ)
+	write := requireScope(models.SCOPE_WRITE)
+
+	r.GET("/products", read, getProduct)
+	r.GET("/product/:id", read, getProductById)
+	r.POST("/products", write, requireUser, addProduct)
+	r.GET("/product/:id/unit-weights", read, getUnitWeights)
+	r.PUT("/product/:id/unit-weights", write, requireUser, setUnitWeight)
+	r.DELETE("/product/:id/unit-weights/:unit", write, requireUser, deleteUnitWeight)
+	r.PUT("/product/:id/density", write, requireUser, setDensity)
+	r.PUT("/product/:id/refuse", write, requireUser, setRefuse)
+	r.GET("/product/:id/units", read, getProductUnits)
+	r.GET("/product/:id/servings", read, getServings)
+	r.PUT("/product/:id/servings", write, requireUser, setServing)
+	r.DELETE("/product/:id/servings/:name", write, requireUser, deleteServing)
+	r.GET("/units/convert", read, convertUnits)
+	r.GET("/dishes", read, getDishes)
+	r.GET("/dish/:id", read, getDishById)
+	r.POST("/dishes", write, requireUser, addDish)
+	r.POST("/nutrition", requireScope(models.SCOPE_NUTRITION), calculateNutrition)
+
+	if _, ok := oidc.ConfigFromEnv(); ok {
+		r.GET("/auth/login", login)
+		r.GET("/auth/callback", loginCallback)
+	}
+	r.POST("/auth/refresh", refreshSession)
+	r.POST("/auth/logout", requireUser, logout)
+	r.GET("/me", requireUser, getMe)
+	r.PATCH("/me", requireUser, updateMe)
+	r.GET("/me/export", requireUser, exportAccount)
+	r.DELETE("/me", requireUser, deleteAccount)
+
+	sessions := r.Group("/sessions", requireUser)
+	sessions.GET("", getSessions)
+	sessions.DELETE("", revokeAllSessions)
+	sessions.DELETE("/:id", revokeSession)
+
+	r.GET("/profile", requireUser, getProfile)
+	r.PUT("/profile", requireUser, updateProfile)
+
+	weight := r.Group("/weight", requireUser)
+	weight.GET("", getWeightLog)
+	weight.POST("", addWeightEntry)
+	weight.DELETE("/:id", deleteWeightEntry)
+
+	r.GET("/activities", read, getActivities)
+	r.POST("/activities", write, requireEditor, addActivity)
+
+	exercise := r.Group("/exercise", requireUser)
+	exercise.GET("", getExercise)
+	exercise.POST("", addExerciseEntry)
+	exercise.DELETE("/:id", deleteExerciseEntry)
+
+	mealPlans := r.Group("/meal-plans", requireUser)
+	mealPlans.POST("/generate", generateMealPlan)
+	mealPlans.GET("", getMealPlans)
+	mealPlans.POST("", createMealPlan)
+	mealPlans.GET("/:id", getMealPlan)
+	mealPlans.PUT("/:id", updateMealPlan)
+	mealPlans.DELETE("/:id", deleteMealPlan)
+	mealPlans.POST("/:id/entries", addMealPlanEntry)
+	mealPlans.PUT("/:id/entries/:entry_id", updateMealPlanEntry)
+	mealPlans.DELETE("/:id/entries/:entry_id", deleteMealPlanEntry)
+	mealPlans.GET("/:id/day", getMealPlanDay)
+	mealPlans.GET("/:id/week", getMealPlanWeek)
+	mealPlans.POST("/:id/copy-last-week", copyLastWeek)
+
+	pantry := r.Group("/pantry", requireUser)
+	pantry.GET("", getPantry)
+	pantry.POST("", addPantryItem)
+	pantry.GET("/expiring", getExpiringPantry)
+	pantry.POST("/cook", cookDish)
+	pantry.POST("/:id/consume", consumePantryItem)
+	pantry.DELETE("/:id", deletePantryItem)
+
+	r.GET("/shopping-lists/:id/events", streamAuth, streamShoppingList)
+	shoppingLists := r.Group("/shopping-lists", requireUser)
+	shoppingLists.POST("/generate", generateShoppingList)
+	shoppingLists.GET("", getShoppingLists)
+	shoppingLists.POST("", createShoppingList)
+	shoppingLists.GET("/:id", getShoppingList)
+	shoppingLists.DELETE("/:id", deleteShoppingList)
+	shoppingLists.POST("/:id/events/ticket", issueStreamTicket)
+	shoppingLists.POST("/:id/members", addShoppingListMember)
+	shoppingLists.DELETE("/:id/members/:user_id", removeShoppingListMember)
+	shoppingLists.POST("/:id/items", addShoppingListItem)
+	shoppingLists.PATCH("/:id/items/:item_id", updateShoppingListItem)
+	shoppingLists.DELETE("/:id/items/:item_id", deleteShoppingListItem)
+
+	diary := r.Group("/diary", requireUser)
+	diary.GET("", getDiary)
+	diary.GET("/summary", getDiarySummary)
+	diary.POST("", addDiaryEntry)
+	diary.DELETE("/:id", deleteDiaryEntry)
+
+	apiKeys := r.Group("/api-keys", requireUser)
+	apiKeys.GET("", getAPIKeys)
+	apiKeys.POST("", createAPIKey)
+	apiKeys.DELETE("/:id", revokeAPIKey)
+
+	moderation := r.Group("/moderation", requireEditor)
+	moderation.GET("/products", getModerationQueue)
+	moderation.POST("/products/:id/review", reviewProduct)
 
 	return r
 }
@@ -0,0 +1,130 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+func refreshSession(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Something went wrong"})
+		return
+	}
+	hash := models.HashToken(req.RefreshToken)
+
+	var s models.Session
+	if err := models.DB.Where("refresh_token_hash = ?", hash).First(&s).Error; err != nil {
+		// Уже использованный refresh-токен - вероятно, его украли. Закрываем сессию
+		if models.DB.Where("previous_refresh_hash = ?", hash).First(&s).Error == nil {
+			s.Revoke()
+			models.DB.Model(&s).Update("revoked_at", s.RevokedAt)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+	if !s.IsActive() {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := s.Rotate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	s.LastUsedAt = time.Now()
+	s.UserAgent = c.Request.UserAgent()
+	s.IP = c.ClientIP()
+
+	// Условие на старый хеш не дает двум параллельным запросам обновить сессию дважды
+	result := models.DB.Model(&s).Where("refresh_token_hash = ?", hash).Select(
+		"token_hash", "access_expires_at", "refresh_token_hash", "previous_refresh_hash",
+		"expires_at", "last_used_at", "user_agent", "ip",
+	).Updates(&s)
+	if result.Error != nil {
+		log.Println("something went wrong with refreshing session:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func getSessions(c *gin.Context) {
+	sessions := []models.Session{}
+	models.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", currentUser(c).ID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions)
+
+	current := currentSession(c)
+	result := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, sessionResponse{Session: s, Current: current != nil && current.ID == s.ID})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+func revokeSession(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var s models.Session
+	if err := models.DB.Where("user_id = ?", currentUser(c).ID).First(&s, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+
+	s.Revoke()
+	if err := models.DB.Model(&s).Update("revoked_at", s.RevokedAt).Error; err != nil {
+		log.Println("something went wrong with revoking session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+func revokeAllSessions(c *gin.Context) {
+	err := models.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", currentUser(c).ID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		log.Println("something went wrong with revoking sessions:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+}
+
+func logout(c *gin.Context) {
+	s := currentSession(c)
+	if s == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Not logged in with a session"})
+		return
+	}
+
+	s.Revoke()
+	if err := models.DB.Model(s).Update("revoked_at", s.RevokedAt).Error; err != nil {
+		log.Println("something went wrong with revoking session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type shoppingDishRequest struct {
+	DishID   uint    `json:"dish_id" binding:"required"`
+	Servings float64 `json:"servings"` // по умолчанию как в рецепте
+}
+
+type shoppingListRequest struct {
+	Dishes []shoppingDishRequest `json:"dishes"`
+
+	// Либо диапазон дат плана питания
+	MealPlanID *uint        `json:"meal_plan_id"`
+	From       *models.Date `json:"from"`
+	To         *models.Date `json:"to"`
+
+	Pantry    []models.ProductAmount `json:"pantry"`     // что уже есть дома
+	UsePantry bool                   `json:"use_pantry"` // вычесть сохраненные запасы
+}
+
+// Что вычитать из списка: переданные количества и, по запросу, сохраненные запасы
+func shoppingPantry(c *gin.Context, req *shoppingListRequest) ([]models.ProductAmount, bool) {
+	pantry := req.Pantry
+	if req.UsePantry {
+		items, err := loadPantry(models.DB, currentUser(c).ID)
+		if err != nil {
+			log.Println("something went wrong with loading pantry:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+			return nil, false
+		}
+		pantry = append(pantry, models.PantryAmounts(items)...)
+	}
+	return pantry, true
+}
+
+// Коэффициент рецепта для нужного числа порций
+func servingsFactor(d *models.Dish, servings float64) float64 {
+	if servings <= 0 || d.Servings <= 0 {
+		return 1
+	}
+	return servings / float64(d.Servings)
+}
+
+// Блюда для списка покупок из запроса
+func shoppingSources(c *gin.Context, req *shoppingListRequest) ([]models.ShoppingSource, bool) {
+	user := currentUser(c)
+	sources := []models.ShoppingSource{}
+
+	for _, item := range req.Dishes {
+		var d models.Dish
+		if err := models.DB.Scopes(models.PreloadProduct("Ingredients.Product")).First(&d, item.DishID).Error; err != nil || !d.IsVisibleTo(user) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Dish not found"})
+			return nil, false
+		}
+		sources = append(sources, models.ShoppingSource{Dish: &d, Factor: servingsFactor(&d, item.Servings)})
+	}
+
+	if req.MealPlanID != nil {
+		if req.From == nil || req.To == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "from and to are required for a meal plan"})
+			return nil, false
+		}
+
+		var p models.MealPlan
+		if err := models.DB.Where("user_id = ?", user.ID).First(&p, *req.MealPlanID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Meal plan not found"})
+			return nil, false
+		}
+
+		entries := []models.MealPlanEntry{}
+		models.DB.Scopes(models.PreloadProduct("Dish.Ingredients.Product")).
+			Where("meal_plan_id = ? AND date >= ? AND date <= ?", p.ID, *req.From, *req.To).
+			Find(&entries)
+		for _, entry := range entries {
+			if entry.Dish == nil {
+				continue
+			}
+			sources = append(sources, models.ShoppingSource{Dish: entry.Dish, Factor: servingsFactor(entry.Dish, entry.Servings)})
+		}
+	}
+
+	if len(sources) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Nothing to shop for"})
+		return nil, false
+	}
+	return sources, true
+}
+
+func generateShoppingList(c *gin.Context) {
+	var req shoppingListRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	sources, ok := shoppingSources(c, &req)
+	if !ok {
+		return
+	}
+	pantry, ok := shoppingPantry(c, &req)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": models.BuildShoppingList(sources, pantry)})
+}
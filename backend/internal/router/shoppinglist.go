@@ -0,0 +1,400 @@
+package router
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Интервал пустых событий, чтобы прокси не закрывали SSE-соединение
+const sseHeartbeat = 25 * time.Second
+
+type createShoppingListRequest struct {
+	Name string `json:"name" binding:"required"`
+	shoppingListRequest
+}
+
+type shoppingListItemRequest struct {
+	ProductID *uint                  `json:"product_id"`
+	Name      string                 `json:"name" binding:"required"`
+	Category  models.ProductCategory `json:"category"`
+	Amount    float64                `json:"amount"`
+	Unit      models.Unit            `json:"unit"`
+}
+
+type updateShoppingListItemRequest struct {
+	Version int          `json:"version" binding:"required"`
+	Checked *bool        `json:"checked"`
+	Name    *string      `json:"name"`
+	Amount  *float64     `json:"amount"`
+	Unit    *models.Unit `json:"unit"`
+}
+
+type shoppingListMemberRequest struct {
+	UserID *uint  `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// Список из :id, доступный текущему пользователю, иначе 404
+func accessibleShoppingList(c *gin.Context) (*models.ShoppingList, bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	var l models.ShoppingList
+	err := models.DB.Preload("Members").First(&l, id).Error
+	if err != nil || !l.HasAccess(currentUser(c).ID) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return nil, false
+	}
+	return &l, true
+}
+
+func ownShoppingList(c *gin.Context) (*models.ShoppingList, bool) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return nil, false
+	}
+	if l.OwnerID != currentUser(c).ID {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can do this"})
+		return nil, false
+	}
+	return l, true
+}
+
+func getShoppingLists(c *gin.Context) {
+	userID := currentUser(c).ID
+	shared := models.DB.Model(&models.ShoppingListMember{}).Select("shopping_list_id").Where("user_id = ?", userID)
+
+	result := []models.ShoppingList{}
+	models.DB.Where("owner_id = ? OR id IN (?)", userID, shared).Order("id").Find(&result)
+
+	c.JSON(http.StatusOK, gin.H{"shopping_lists": result})
+}
+
+func createShoppingList(c *gin.Context) {
+	var req createShoppingListRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	l := models.ShoppingList{OwnerID: currentUser(c).ID, Name: req.Name}
+	if err := l.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Без блюд и плана создается пустой список
+	if len(req.Dishes) > 0 || req.MealPlanID != nil {
+		sources, ok := shoppingSources(c, &req.shoppingListRequest)
+		if !ok {
+			return
+		}
+		pantry, ok := shoppingPantry(c, &req.shoppingListRequest)
+		if !ok {
+			return
+		}
+		l.Items = models.ShoppingListItemsFrom(models.BuildShoppingList(sources, pantry))
+	}
+
+	if err := models.DB.Create(&l).Error; err != nil {
+		log.Println("something went wrong with creating shopping list:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusCreated, l)
+}
+
+func getShoppingList(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+	models.DB.Where("shopping_list_id = ?", l.ID).Order("category, name, id").Find(&l.Items)
+
+	c.JSON(http.StatusOK, l)
+}
+
+func deleteShoppingList(c *gin.Context) {
+	l, ok := ownShoppingList(c)
+	if !ok {
+		return
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shopping_list_id = ?", l.ID).Delete(&models.ShoppingListItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shopping_list_id = ?", l.ID).Delete(&models.ShoppingListMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(l).Error
+	})
+	if err != nil {
+		log.Println("something went wrong with deleting shopping list:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	shoppingListEvents.publish(l.ID, "list_deleted", gin.H{"id": l.ID})
+	shoppingListEvents.close(l.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+func addShoppingListMember(c *gin.Context) {
+	l, ok := ownShoppingList(c)
+	if !ok {
+		return
+	}
+
+	var req shoppingListMemberRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var u models.User
+	var err error
+	switch {
+	case req.UserID != nil:
+		err = models.DB.First(&u, *req.UserID).Error
+	case req.Email != "":
+		err = models.DB.Where("email = ?", req.Email).First(&u).Error
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "user_id or email is required"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+	if u.ID == l.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Owner already has access"})
+		return
+	}
+
+	member := models.ShoppingListMember{ShoppingListID: l.ID, UserID: u.ID}
+	if err := models.DB.FirstOrCreate(&member, member).Error; err != nil {
+		log.Println("something went wrong with adding shopping list member:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	member.User = &u
+	c.JSON(http.StatusOK, member)
+}
+
+// Владелец может убрать любого участника, участник - только себя
+func removeShoppingListMember(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	memberID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	if l.OwnerID != user.ID && memberID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can do this"})
+		return
+	}
+
+	result := models.DB.Where("shopping_list_id = ? AND user_id = ?", l.ID, memberID).Delete(&models.ShoppingListMember{})
+	if result.Error != nil {
+		log.Println("something went wrong with removing shopping list member:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+
+	// Бывший участник получает событие последним и отключается от потока
+	shoppingListEvents.publish(l.ID, "member_removed", gin.H{"user_id": memberID})
+	shoppingListEvents.closeUser(l.ID, memberID)
+	c.JSON(http.StatusOK, gin.H{"message": "Removed"})
+}
+
+func addShoppingListItem(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	var req shoppingListItemRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	item := models.ShoppingListItem{
+		ShoppingListID: l.ID,
+		ProductID:      req.ProductID,
+		Name:           req.Name,
+		Category:       req.Category,
+		Amount:         req.Amount,
+		Unit:           req.Unit,
+		Version:        1,
+	}
+	if err := item.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.DB.Create(&item).Error; err != nil {
+		log.Println("something went wrong with creating shopping list item:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	shoppingListEvents.publish(l.ID, "item_added", item)
+	c.JSON(http.StatusCreated, item)
+}
+
+// Изменение позиции принимается, только если клиент видел последнюю версию.
+// Иначе возвращается 409 с актуальным состоянием
+func updateShoppingListItem(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	var req updateShoppingListItemRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	itemID, ok := paramID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	var item models.ShoppingListItem
+	if err := models.DB.Where("shopping_list_id = ?", l.ID).First(&item, itemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	if item.Version != req.Version {
+		c.JSON(http.StatusConflict, gin.H{"message": "Item was changed by someone else", "item": item})
+		return
+	}
+
+	if req.Checked != nil {
+		item.Checked = *req.Checked
+		item.CheckedBy = nil
+		if item.Checked {
+			item.CheckedBy = &currentUser(c).ID
+		}
+	}
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Amount != nil {
+		item.Amount = *req.Amount
+	}
+	if req.Unit != nil {
+		item.Unit = *req.Unit
+	}
+	if req.Amount != nil || req.Unit != nil {
+		// Число упаковок считалось от прежнего количества
+		item.Packages, item.PackageUnit = 0, ""
+	}
+	if err := item.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	item.Version++
+
+	result := models.DB.Model(&item).Where("version = ?", req.Version).
+		Select("checked", "checked_by", "name", "amount", "unit", "packages", "package_unit", "version", "updated_at").
+		Updates(&item)
+	if result.Error != nil {
+		log.Println("something went wrong with updating shopping list item:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		// Кто-то успел изменить позицию между чтением и записью
+		models.DB.First(&item, item.ID)
+		c.JSON(http.StatusConflict, gin.H{"message": "Item was changed by someone else", "item": item})
+		return
+	}
+
+	shoppingListEvents.publish(l.ID, "item_updated", item)
+	c.JSON(http.StatusOK, item)
+}
+
+func deleteShoppingListItem(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	itemID, ok := paramID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	var item models.ShoppingListItem
+	if err := models.DB.Where("shopping_list_id = ?", l.ID).First(&item, itemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	if err := models.DB.Delete(&item).Error; err != nil {
+		log.Println("something went wrong with deleting shopping list item:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	shoppingListEvents.publish(l.ID, "item_deleted", gin.H{"id": item.ID})
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+// Поток изменений списка (Server-Sent Events). Браузер подключается
+// с ?ticket= из POST /shopping-lists/:id/events/ticket. Первым приходит
+// полный снимок списка, дальше - события item_added/item_updated/item_deleted
+// и member_removed
+func streamShoppingList(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	events := shoppingListEvents.subscribe(l.ID, currentUser(c).ID)
+	defer shoppingListEvents.unsubscribe(l.ID, events)
+
+	models.DB.Where("shopping_list_id = ?", l.ID).Order("category, name, id").Find(&l.Items)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", l)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event.Data)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Одноразовый билет на подключение к потоку событий списка. EventSource
+// в браузере не умеет передавать заголовки, а токен доступа в URL попал
+// бы в логи, поэтому в ?ticket= передается только этот билет
+type streamTicket struct {
+	userID    uint
+	listID    uint
+	expiresAt time.Time
+}
+
+const streamTicketTTL = 30 * time.Second
+
+// Билеты хранятся в памяти, как и подписчики listBroker
+type ticketStore struct {
+	mu      sync.Mutex
+	tickets map[string]streamTicket // по хешу билета
+}
+
+var streamTickets = &ticketStore{tickets: map[string]streamTicket{}}
+
+func (s *ticketStore) issue(userID, listID uint) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(buf)
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for hash, t := range s.tickets {
+		if now.After(t.expiresAt) {
+			delete(s.tickets, hash)
+		}
+	}
+	s.tickets[models.HashToken(ticket)] = streamTicket{userID: userID, listID: listID, expiresAt: expiresAt}
+	return ticket, expiresAt, nil
+}
+
+// Погасить билет. Билет действует один раз и только для своего списка
+func (s *ticketStore) redeem(ticket string, listID uint) (uint, bool) {
+	hash := models.HashToken(ticket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tickets[hash]
+	if !ok {
+		return 0, false
+	}
+	delete(s.tickets, hash)
+	if t.listID != listID || time.Now().After(t.expiresAt) {
+		return 0, false
+	}
+	return t.userID, true
+}
+
+func issueStreamTicket(c *gin.Context) {
+	l, ok := accessibleShoppingList(c)
+	if !ok {
+		return
+	}
+
+	ticket, expiresAt, err := streamTickets.issue(currentUser(c).ID, l.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_at": expiresAt})
+}
+
+// Авторизация потока событий: по ?ticket= или, для клиентов,
+// которые умеют передавать заголовки, обычным Bearer-токеном
+func streamAuth(c *gin.Context) {
+	ticket := c.Query("ticket")
+	if ticket == "" {
+		requireUser(c)
+		return
+	}
+
+	listID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid ticket"})
+		return
+	}
+	userID, ok := streamTickets.redeem(ticket, uint(listID))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid ticket"})
+		return
+	}
+
+	var u models.User
+	if err := models.DB.First(&u, userID).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid ticket"})
+		return
+	}
+	c.Set(userKey, &u)
+	c.Next()
+}
@@ -0,0 +1,262 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+type unitWeightRequest struct {
+	Unit         models.Unit `json:"unit" binding:"required"`
+	Grams        float64     `json:"grams" binding:"required"`
+	DrainedGrams *float64    `json:"drained_grams"` // вес консервов без заливки
+}
+
+type servingRequest struct {
+	Name  string  `json:"name" binding:"required"`
+	Grams float64 `json:"grams" binding:"required"`
+}
+
+type densityRequest struct {
+	Density *float64 `json:"density"` // null - вернуться к плотности по категории
+}
+
+type refuseRequest struct {
+	RefusePercent *float64 `json:"refuse_percent"` // null - продукт без отходов
+}
+
+// Продукт из :id, видимый пользователю
+func visibleProduct(c *gin.Context) (*models.Product, bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	var p models.Product
+	if err := models.DB.Scopes(models.PreloadProduct("")).First(&p, id).Error; err != nil || !p.IsVisibleTo(currentUser(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return nil, false
+	}
+	return &p, true
+}
+
+// Продукт из :id, который пользователь может менять
+func editableProduct(c *gin.Context) (*models.Product, bool) {
+	p, ok := visibleProduct(c)
+	if !ok {
+		return nil, false
+	}
+	if !p.CanBeEditedBy(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+		return nil, false
+	}
+	return p, true
+}
+
+func getProductUnits(c *gin.Context) {
+	p, ok := visibleProduct(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"units": p.AvailableUnits()})
+}
+
+func getUnitWeights(c *gin.Context) {
+	p, ok := visibleProduct(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unit_weights": p.UnitWeights})
+}
+
+func setUnitWeight(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	var req unitWeightRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	w := models.ProductUnitWeight{ProductID: p.ID, Unit: req.Unit, Grams: req.Grams, DrainedGrams: req.DrainedGrams}
+	if err := w.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := models.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "unit"}},
+		DoUpdates: clause.AssignmentColumns([]string{"grams", "drained_grams"}),
+	}).Create(&w).Error
+	if err != nil {
+		log.Println("something went wrong with saving unit weight:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+func deleteUnitWeight(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	result := models.DB.Where("product_id = ? AND unit = ?", p.ID, c.Param("unit")).Delete(&models.ProductUnitWeight{})
+	if result.Error != nil {
+		log.Println("something went wrong with deleting unit weight:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
+
+func setDensity(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	var req densityRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if req.Density != nil && *req.Density <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "density must be positive"})
+		return
+	}
+
+	if err := models.DB.Model(p).Update("density", req.Density).Error; err != nil {
+		log.Println("something went wrong with saving density:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Перевести количество между единицами: ?amount=&from=&to=, для перевода
+// между весом и объемом или штуками нужен ?product_id=
+func convertUnits(c *gin.Context) {
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "amount must be a positive number"})
+		return
+	}
+
+	var p *models.Product
+	if value := c.Query("product_id"); value != "" {
+		id, ok := parseID(c, value, "product_id")
+		if !ok {
+			return
+		}
+
+		var product models.Product
+		if err := models.DB.Scopes(models.PreloadProduct("")).First(&product, id).Error; err != nil || !product.IsVisibleTo(currentUser(c)) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+			return
+		}
+		p = &product
+	}
+
+	result, err := models.ConvertUnits(p, amount, models.Unit(c.Query("from")), models.Unit(c.Query("to")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func setRefuse(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	var req refuseRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err := models.ValidateRefusePercent(req.RefusePercent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := models.DB.Model(p).Update("refuse_percent", req.RefusePercent).Error; err != nil {
+		log.Println("something went wrong with saving refuse percent:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func getServings(c *gin.Context) {
+	p, ok := visibleProduct(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"servings": p.Servings})
+}
+
+func setServing(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	var req servingRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	s := models.ProductServing{ProductID: p.ID, Name: req.Name, Grams: req.Grams}
+	if err := s.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err := models.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"grams"}),
+	}).Create(&s).Error
+	if err != nil {
+		log.Println("something went wrong with saving serving:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func deleteServing(c *gin.Context) {
+	p, ok := editableProduct(c)
+	if !ok {
+		return
+	}
+
+	name := strings.ToLower(strings.TrimSpace(c.Param("name")))
+	result := models.DB.Where("product_id = ? AND name = ?", p.ID, name).Delete(&models.ProductServing{})
+	if result.Error != nil {
+		log.Println("something went wrong with deleting serving:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cr1phy/fitly/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+type weightEntryRequest struct {
+	Date     *models.Date `json:"date"`
+	WeightKg float64      `json:"weight_kg" binding:"required"`
+}
+
+func getWeightLog(c *gin.Context) {
+	user := currentUser(c)
+
+	query := models.DB.Where("user_id = ?", user.ID)
+	if from := c.Query("from"); from != "" {
+		date, err := models.ParseDate(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		query = query.Where("date >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := models.ParseDate(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		query = query.Where("date <= ?", date)
+	}
+
+	entries := []models.WeightEntry{}
+	query.Order("date").Find(&entries)
+
+	var goal *float64
+	profile, err := loadProfile(user.ID)
+	if err != nil {
+		log.Println("something went wrong with loading profile:", err)
+	}
+	if profile != nil {
+		goal = profile.GoalWeightKg
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"trend":   models.CalculateWeightTrend(entries, goal),
+	})
+}
+
+func addWeightEntry(c *gin.Context) {
+	var req weightEntryRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	entry := models.WeightEntry{UserID: user.ID, Date: models.Today(), WeightKg: req.WeightKg}
+	if req.Date != nil {
+		entry.Date = *req.Date
+	}
+	if err := entry.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Одно измерение в день: повторная запись заменяет прежнюю
+	err := models.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"weight_kg"}),
+	}).Create(&entry).Error
+	if err != nil {
+		log.Println("something went wrong with saving weight entry:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+
+	// Свежее измерение обновляет вес в профиле, от него считаются нормы
+	var latest models.WeightEntry
+	if models.DB.Where("user_id = ?", user.ID).Order("date DESC").First(&latest).Error == nil && latest.Date.Equal(entry.Date.Time) {
+		models.DB.Model(&models.Profile{}).Where("user_id = ?", user.ID).Update("weight_kg", entry.WeightKg)
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func deleteWeightEntry(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	user := currentUser(c)
+	result := models.DB.Where("user_id = ?", user.ID).Delete(&models.WeightEntry{}, id)
+	if result.Error != nil {
+		log.Println("something went wrong with deleting weight entry:", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Something went wrong"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		return
+	}
+
+	// В профиле остается вес из последнего сохранившегося измерения
+	var latest models.WeightEntry
+	if models.DB.Where("user_id = ?", user.ID).Order("date DESC").First(&latest).Error == nil {
+		models.DB.Model(&models.Profile{}).Where("user_id = ?", user.ID).Update("weight_kg", latest.WeightKg)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted"})
+}